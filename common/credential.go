@@ -1,5 +1,17 @@
 package common
 
+import (
+	"fmt"
+	"os"
+)
+
+// Environment variables read by NewCredentialFromEnv
+const (
+	EnvSecretId  = "TENCENTCLOUD_SECRET_ID"
+	EnvSecretKey = "TENCENTCLOUD_SECRET_KEY"
+	EnvToken     = "TENCENTCLOUD_TOKEN"
+)
+
 type Credential struct {
 	SecretId  string
 	SecretKey string
@@ -21,6 +33,20 @@ func NewTokenCredential(secretId, secretKey, token string) *Credential {
 	}
 }
 
+// NewCredentialFromEnv reads the credential from TENCENTCLOUD_SECRET_ID, TENCENTCLOUD_SECRET_KEY
+// and the optional TENCENTCLOUD_TOKEN environment variables.
+func NewCredentialFromEnv() (*Credential, error) {
+	secretId := os.Getenv(EnvSecretId)
+	if secretId == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", EnvSecretId)
+	}
+	secretKey := os.Getenv(EnvSecretKey)
+	if secretKey == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", EnvSecretKey)
+	}
+	return NewTokenCredential(secretId, secretKey, os.Getenv(EnvToken)), nil
+}
+
 func (c *Credential) GetCredentialParams() map[string]string {
 	p := map[string]string{
 		"SecretId": c.SecretId,
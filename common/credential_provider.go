@@ -0,0 +1,25 @@
+package common
+
+import "fmt"
+
+// CredentialProvider returns the credential to sign a request with, it is called
+// for every new session so implementations may rotate STS tokens.
+type CredentialProvider interface {
+	GetCredential() (*Credential, error)
+}
+
+type staticCredentialProvider struct {
+	credential *Credential
+}
+
+// NewStaticCredentialProvider wraps a fixed credential as a CredentialProvider
+func NewStaticCredentialProvider(credential *Credential) CredentialProvider {
+	return &staticCredentialProvider{credential: credential}
+}
+
+func (p *staticCredentialProvider) GetCredential() (*Credential, error) {
+	if p.credential == nil {
+		return nil, fmt.Errorf("credential is nil")
+	}
+	return p.credential, nil
+}
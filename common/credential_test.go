@@ -0,0 +1,53 @@
+package common
+
+import "testing"
+
+func TestNewCredentialFromEnv(t *testing.T) {
+	t.Setenv(EnvSecretId, "AKIDexample")
+	t.Setenv(EnvSecretKey, "secret")
+	t.Setenv(EnvToken, "token")
+	credential, err := NewCredentialFromEnv()
+	if err != nil {
+		t.Fatalf("NewCredentialFromEnv() error = %v", err)
+	}
+	want := Credential{SecretId: "AKIDexample", SecretKey: "secret", Token: "token"}
+	if *credential != want {
+		t.Errorf("NewCredentialFromEnv() = %+v, want %+v", *credential, want)
+	}
+}
+
+func TestNewCredentialFromEnvWithoutToken(t *testing.T) {
+	t.Setenv(EnvSecretId, "AKIDexample")
+	t.Setenv(EnvSecretKey, "secret")
+	t.Setenv(EnvToken, "")
+	credential, err := NewCredentialFromEnv()
+	if err != nil {
+		t.Fatalf("NewCredentialFromEnv() error = %v", err)
+	}
+	if credential.Token != "" {
+		t.Errorf("Token = %q, want empty", credential.Token)
+	}
+	if _, ok := credential.GetCredentialParams()["Token"]; ok {
+		t.Errorf("GetCredentialParams() has a Token without one set")
+	}
+}
+
+func TestNewCredentialFromEnvMissing(t *testing.T) {
+	tests := []struct {
+		name      string
+		secretId  string
+		secretKey string
+	}{
+		{"no secret id", "", "secret"},
+		{"no secret key", "AKIDexample", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvSecretId, tt.secretId)
+			t.Setenv(EnvSecretKey, tt.secretKey)
+			if credential, err := NewCredentialFromEnv(); err == nil {
+				t.Errorf("NewCredentialFromEnv() = %+v, want an error", credential)
+			}
+		})
+	}
+}
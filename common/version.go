@@ -0,0 +1,7 @@
+package common
+
+// Version is the version of this SDK
+const Version = "1.0.0"
+
+// UserAgent identifies this SDK in the handshake of its requests
+const UserAgent = "tencentcloud-speech-sdk-go/" + Version
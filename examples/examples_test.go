@@ -0,0 +1,53 @@
+package examples
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const modulePath = "github.com/showntop/tencentcloud-speech-sdk-go"
+
+// TestExamplesImportModulePath checks the examples import the SDK by the module path, so
+// they can be copied as they are
+func TestExamplesImportModulePath(t *testing.T) {
+	files, err := filepath.Glob("*/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no example found")
+	}
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if strings.Contains(path, "tencentcloud-speech-sdk-go") && !strings.HasPrefix(path, modulePath+"/") {
+				t.Errorf("%s imports %s, want a path under %s", file, path, modulePath)
+			}
+		}
+	}
+}
+
+// TestExamplesBuild compiles the examples against the module
+func TestExamplesBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the examples")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cmd := exec.Command(goTool, "build", "-o", os.DevNull, "./...")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build ./examples/...: %v\n%s", err, out)
+	}
+}
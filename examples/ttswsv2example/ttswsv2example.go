@@ -85,5 +85,6 @@ func processWs(id int, wg *sync.WaitGroup) {
 	synthesizer.Send("参差不齐")
 	synthesizer.Send("的。需要缩小较弱地域和较强地域的")
 	synthesizer.Send("差距。")
+	synthesizer.Complete()
 	synthesizer.Wait()
 }
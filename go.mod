@@ -1,6 +1,6 @@
 module github.com/showntop/tencentcloud-speech-sdk-go
 
-go 1.12
+go 1.18
 
 require (
 	github.com/google/uuid v1.1.2
@@ -0,0 +1,92 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backoff computes the delays of PrepareWithRetry, set it with WithBackoff. NextDelay
+// returns the delay before the retry following the attempt-th failure, attempt counting
+// from 1; Reset is called before the first attempt, for implementations keeping state.
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+	Reset()
+}
+
+// ExponentialBackoff waits Initial after the first failure, multiplied by Multiplier after
+// each following one, up to Max. A zero Multiplier doubles, a zero Max means no cap. The
+// default Backoff is an ExponentialBackoff of 500ms up to 10s.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+// NewExponentialBackoff creates instance of ExponentialBackoff
+func NewExponentialBackoff(initial, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{Initial: initial, Max: max, Multiplier: 2}
+}
+
+// NextDelay returns Initial * Multiplier^(attempt-1), capped at Max
+func (b *ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if b.Max > 0 && delay >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(delay)
+}
+
+// Reset does nothing, the delays only depend on attempt
+func (b *ExponentialBackoff) Reset() {}
+
+// ConstantBackoff waits Delay between all attempts
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay returns Delay
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+// Reset does nothing
+func (b ConstantBackoff) Reset() {}
+
+var defaultBackoff = &ExponentialBackoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2}
+
+// PrepareWithRetry calls Prepare up to attempts times while it fails with an error
+// IsRetryable accepts, waiting the delays of the Backoff (see WithBackoff) in between, and
+// returns the error of the last attempt. ctx only bounds the waits: when it is done
+// first, its error is returned.
+func (synthesizer *SpeechWsv2Synthesizer) PrepareWithRetry(ctx context.Context, attempts int) error {
+	backoff := synthesizer.backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	backoff.Reset()
+	for attempt := 1; ; attempt++ {
+		err := synthesizer.Prepare()
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return err
+		}
+		synthesizer.debug("retry", 0, fmt.Sprintf("prepare attempt %d failed: %s", attempt, err.Error()))
+		timer := time.NewTimer(backoff.NextDelay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
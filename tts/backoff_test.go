@@ -0,0 +1,118 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		backoff *ExponentialBackoff
+		want    []time.Duration
+	}{
+		{NewExponentialBackoff(100*time.Millisecond, time.Second),
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}},
+		{&ExponentialBackoff{Initial: 10 * time.Millisecond, Multiplier: 3},
+			[]time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 90 * time.Millisecond}},
+		{&ExponentialBackoff{Initial: time.Second, Max: 500 * time.Millisecond},
+			[]time.Duration{500 * time.Millisecond, 500 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.backoff.NextDelay(i + 1); got != want {
+				t.Errorf("%+v NextDelay(%d) = %v, want %v", *tt.backoff, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff{Delay: 50 * time.Millisecond}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := b.NextDelay(attempt); got != b.Delay {
+			t.Errorf("NextDelay(%d) = %v, want %v", attempt, got, b.Delay)
+		}
+	}
+}
+
+// scriptedBackoff returns its delays in turn and records the calls
+type scriptedBackoff struct {
+	delays   []time.Duration
+	attempts []int
+	resets   int
+}
+
+func (b *scriptedBackoff) NextDelay(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return b.delays[len(b.attempts)-1]
+}
+
+func (b *scriptedBackoff) Reset() {
+	b.resets++
+	b.attempts = nil
+}
+
+// failingThenSynthesize fails the first failures sessions dialed with code, then
+// synthesizes; it returns the count of sessions dialed
+func failingThenSynthesize(t *testing.T, code, failures int32) *int32 {
+	var dials int32
+	mockServer(t, func(conn *websocket.Conn) {
+		if atomic.AddInt32(&dials, 1) <= failures {
+			conn.WriteJSON(map[string]interface{}{"code": code, "message": "failed"})
+			drain(conn)
+			return
+		}
+		synthesize(conn, pcm(320))
+	})
+	return &dials
+}
+
+func TestPrepareWithRetryUsesBackoff(t *testing.T) {
+	dials := failingThenSynthesize(t, 5000, 2)
+	backoff := &scriptedBackoff{delays: []time.Duration{20 * time.Millisecond, 40 * time.Millisecond}}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithBackoff(backoff))
+	start := time.Now()
+	if err := s.PrepareWithRetry(context.Background(), 5); err != nil {
+		t.Fatalf("PrepareWithRetry() error = %v", err)
+	}
+	elapsed := time.Since(start)
+	s.Close()
+	if n := atomic.LoadInt32(dials); n != 3 {
+		t.Errorf("%d connections dialed, want 3", n)
+	}
+	if backoff.resets != 1 || !reflect.DeepEqual(backoff.attempts, []int{1, 2}) {
+		t.Errorf("Reset called %d times, NextDelay with %v, want once and [1 2]", backoff.resets, backoff.attempts)
+	}
+	if elapsed < 60*time.Millisecond {
+		t.Errorf("PrepareWithRetry returned after %v, want the 60ms of the backoff waited", elapsed)
+	}
+}
+
+func TestPrepareWithRetryPermanentError(t *testing.T) {
+	dials := failingThenSynthesize(t, 4002, 1)
+	backoff := &scriptedBackoff{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithBackoff(backoff))
+	if err := s.PrepareWithRetry(context.Background(), 3); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("PrepareWithRetry() = %v, want %v", err, ErrAuthFailed)
+	}
+	if n := atomic.LoadInt32(dials); n != 1 || len(backoff.attempts) != 0 {
+		t.Errorf("%d connections dialed, %d delays asked, want a single attempt", n, len(backoff.attempts))
+	}
+}
+
+func TestPrepareWithRetryContext(t *testing.T) {
+	failingThenSynthesize(t, 5000, 3)
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{},
+		WithBackoff(ConstantBackoff{Delay: time.Hour}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.PrepareWithRetry(ctx, 3); err != context.DeadlineExceeded {
+		t.Fatalf("PrepareWithRetry() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
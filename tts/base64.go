@@ -0,0 +1,61 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Base64AudioWriter base64-encodes every Write on its own, padding included, and
+// follows it with Delimiter, e.g. to relay audio frames as SSE data lines. As each
+// chunk is complete, a client decodes the chunks one by one as they arrive, which
+// concatenated base64 streams would not allow once a chunk ends with padding. Use
+// DecodeBase64Audio to decode the whole output.
+type Base64AudioWriter struct {
+	W         io.Writer
+	Delimiter []byte
+}
+
+// NewBase64AudioWriter creates instance of Base64AudioWriter, a nil delimiter means "\n"
+func NewBase64AudioWriter(w io.Writer, delimiter []byte) *Base64AudioWriter {
+	if delimiter == nil {
+		delimiter = []byte("\n")
+	}
+	return &Base64AudioWriter{W: w, Delimiter: delimiter}
+}
+
+// Write writes the base64 encoding of p followed by the delimiter in a single Write to W
+func (w *Base64AudioWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(p)), base64.StdEncoding.EncodedLen(len(p))+len(w.Delimiter))
+	base64.StdEncoding.Encode(buf, p)
+	buf = append(buf, w.Delimiter...)
+	if _, err := w.W.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// DecodeBase64Audio decodes the output of a Base64AudioWriter using the same delimiter,
+// a nil delimiter means "\n". Empty chunks are skipped.
+func DecodeBase64Audio(data []byte, delimiter []byte) ([]byte, error) {
+	if delimiter == nil {
+		delimiter = []byte("\n")
+	}
+	var audio []byte
+	for i, chunk := range bytes.Split(data, delimiter) {
+		if len(chunk) == 0 {
+			continue
+		}
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(chunk)))
+		n, err := base64.StdEncoding.Decode(decoded, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %s", i, err.Error())
+		}
+		audio = append(audio, decoded[:n]...)
+	}
+	return audio, nil
+}
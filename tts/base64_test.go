@@ -0,0 +1,60 @@
+package tts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBase64AudioRoundTrip(t *testing.T) {
+	// frame lengths leaving 0, 1 and 2 bytes over a multiple of 3, so some chunks are padded
+	frames := [][]byte{pcm(300), pcm(301), pcm(302), pcm(1)}
+	for _, delimiter := range [][]byte{nil, []byte("\n\n"), []byte("|")} {
+		var out bytes.Buffer
+		w := NewBase64AudioWriter(&out, delimiter)
+		var want []byte
+		for _, frame := range frames {
+			if n, err := w.Write(frame); err != nil || n != len(frame) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(frame))
+			}
+			want = append(want, frame...)
+		}
+		if got := strings.Count(out.String(), string(w.Delimiter)); got != len(frames) {
+			t.Errorf("delimiter %q: %d chunks written, want one per frame", w.Delimiter, got)
+		}
+		got, err := DecodeBase64Audio(out.Bytes(), delimiter)
+		if err != nil {
+			t.Fatalf("DecodeBase64Audio() error = %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("delimiter %q: decoded %d bytes, want the %d bytes written", w.Delimiter, len(got), len(want))
+		}
+	}
+}
+
+func TestBase64AudioWriterAsAudioWriter(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.AudioWriter = NewBase64AudioWriter(&out, nil)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.binary(pcm(161))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	got, err := DecodeBase64Audio(out.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("DecodeBase64Audio() error = %v", err)
+	}
+	if !bytes.Equal(got, append(pcm(320), pcm(161)...)) {
+		t.Errorf("decoded %d bytes, want the 481 bytes received", len(got))
+	}
+}
+
+func TestDecodeBase64AudioInvalid(t *testing.T) {
+	if _, err := DecodeBase64Audio([]byte("AAAA\n!!!!\n"), nil); err == nil || !strings.Contains(err.Error(), "chunk 1") {
+		t.Errorf("DecodeBase64Audio() error = %v, want the invalid chunk reported", err)
+	}
+}
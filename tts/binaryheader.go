@@ -0,0 +1,44 @@
+package tts
+
+import (
+	"encoding/binary"
+	"encoding/json"
+)
+
+// maxBinaryHeaderBytes bounds the header length accepted by splitBinaryHeader
+const maxBinaryHeaderBytes = 64 * 1024
+
+// AudioFrameMeta is the JSON header of an audio frame with WithBinaryHeaders. The fields
+// the header lacks are left zero, Raw holds it as received for the others.
+type AudioFrameMeta struct {
+	MessageId string          `json:"message_id"`
+	Sequence  int64           `json:"sequence"`
+	Raw       json.RawMessage `json:"-"`
+}
+
+// SpeechWsv2AudioMetaListener can be implemented in addition to SpeechWsv2SynthesisListener
+// to get the header of each audio frame with WithBinaryHeaders. OnAudioFrameMeta is called
+// after OnAudioResult with the same audio, the header removed; frames without a valid
+// header are taken as raw audio and don't call it.
+type SpeechWsv2AudioMetaListener interface {
+	OnAudioFrameMeta(meta AudioFrameMeta, data []byte)
+}
+
+// splitBinaryHeader splits a binary frame made of the 4 bytes big-endian length of a JSON
+// object, the object and the audio. ok is false when data doesn't start with such a header.
+func splitBinaryHeader(data []byte) (meta *AudioFrameMeta, audio []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, data, false
+	}
+	n := binary.BigEndian.Uint32(data)
+	if n < 2 || n > maxBinaryHeaderBytes || int64(n) > int64(len(data)-4) {
+		return nil, data, false
+	}
+	header := data[4 : 4+n]
+	meta = &AudioFrameMeta{}
+	if header[0] != '{' || json.Unmarshal(header, meta) != nil {
+		return nil, data, false
+	}
+	meta.Raw = json.RawMessage(header)
+	return meta, data[4+n:], true
+}
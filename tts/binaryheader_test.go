@@ -0,0 +1,96 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// headerFrame returns a binary frame of header, length-prefixed, followed by audio
+func headerFrame(header string, audio []byte) []byte {
+	frame := make([]byte, 4, 4+len(header)+len(audio))
+	binary.BigEndian.PutUint32(frame, uint32(len(header)))
+	frame = append(frame, header...)
+	return append(frame, audio...)
+}
+
+func TestSplitBinaryHeader(t *testing.T) {
+	header := `{"message_id":"m1","sequence":3,"extra":true}`
+	meta, audio, ok := splitBinaryHeader(headerFrame(header, pcm(320)))
+	if !ok {
+		t.Fatal("splitBinaryHeader() ok = false for a valid header")
+	}
+	if meta.MessageId != "m1" || meta.Sequence != 3 || string(meta.Raw) != header {
+		t.Errorf("meta = %+v, want message m1, sequence 3 and the raw header", meta)
+	}
+	if !bytes.Equal(audio, pcm(320)) {
+		t.Errorf("audio = %d bytes, want the 320 bytes after the header", len(audio))
+	}
+
+	for name, frame := range map[string][]byte{
+		"short":          {0, 0, 1},
+		"raw audio":      pcm(320),
+		"length too big": append([]byte{0, 0, 1, 0}, `{}`...),
+		"not an object":  headerFrame(`[1]`, pcm(4)),
+		"invalid json":   headerFrame(`{"sequence":`, pcm(4)),
+	} {
+		if meta, audio, ok := splitBinaryHeader(frame); ok || meta != nil || !bytes.Equal(audio, frame) {
+			t.Errorf("%s: splitBinaryHeader() = %v, %d bytes, %v, want the frame as raw audio", name, meta, len(audio), ok)
+		}
+	}
+}
+
+// metaListener records the headers of the audio frames
+type metaListener struct {
+	recordListener
+	metas []AudioFrameMeta
+	sizes []int
+}
+
+func (l *metaListener) OnAudioFrameMeta(meta AudioFrameMeta, data []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.metas = append(l.metas, meta)
+	l.sizes = append(l.sizes, len(data))
+	l.record("meta %s", meta.MessageId)
+}
+
+func TestBinaryHeaders(t *testing.T) {
+	conn := newFakeConn()
+	listener := &metaListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithBinaryHeaders())
+	startFake(s, conn)
+	conn.binary(headerFrame(`{"message_id":"m1","sequence":1}`, pcm(320)))
+	conn.binary(pcm(160))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []string{"start", "audio 320", "meta m1", "audio 160", "end"}
+	if got := listener.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(listener.sizes, []int{320}) || listener.metas[0].Sequence != 1 {
+		t.Errorf("OnAudioFrameMeta got %+v with %v bytes, want sequence 1 with 320", listener.metas, listener.sizes)
+	}
+	if got := listener.audioBytes(); !bytes.Equal(got, append(pcm(320), pcm(160)...)) {
+		t.Errorf("OnAudioResult got %d bytes, want the audio without the header", len(got))
+	}
+}
+
+func TestBinaryHeadersOff(t *testing.T) {
+	frame := headerFrame(`{"message_id":"m1"}`, pcm(320))
+	conn := newFakeConn()
+	listener := &metaListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(frame)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(listener.metas) != 0 || !bytes.Equal(listener.audioBytes(), frame) {
+		t.Errorf("OnAudioFrameMeta got %v, OnAudioResult %d bytes, want the whole frame as audio", listener.metas, len(listener.audioBytes()))
+	}
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker stops connecting to an endpoint that keeps failing: after Threshold
+// consecutive failed Prepare, the following ones fail at once with ErrCircuitOpen for
+// Cooldown. Then a single Prepare is let through as a probe, closing the breaker when it
+// succeeds or opening it for another Cooldown when it fails. Only the failures IsRetryable
+// classifies as retryable count, a rejected request such as a bad signature shows the
+// endpoint is up. It is safe for concurrent use, share one between the synthesizers of an
+// endpoint with WithCircuitBreaker.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mutex    sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	probing  bool
+}
+
+// NewCircuitBreaker creates instance of CircuitBreaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// Open reports whether Prepare currently fails with ErrCircuitOpen, a probe being
+// allowed or running counts as open
+func (b *CircuitBreaker) Open() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// allow reports whether a connection may be attempted
+func (b *CircuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record counts the outcome of an allowed connection attempt
+func (b *CircuitBreaker) record(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err == nil || !IsRetryable(err) {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.probing = false
+		return
+	}
+	b.failures++
+	if b.probing || b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
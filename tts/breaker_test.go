@@ -0,0 +1,86 @@
+package tts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCircuitBreakerOpensAfterFailures(t *testing.T) {
+	const cooldown = 100 * time.Millisecond
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		drain(conn)
+	})
+	accepting := wsHostv2
+	wsHostv2 = refusedHost(t)
+	breaker := NewCircuitBreaker(2, cooldown)
+	prepare := func() error {
+		// a fresh synthesizer each time: the breaker is shared
+		s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithCircuitBreaker(breaker))
+		s.ConnectTimeout = time.Second
+		err := s.Prepare()
+		if err == nil {
+			s.Abort()
+		}
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := prepare(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Prepare() #%d error = %v, want the dial refused", i+1, err)
+		}
+	}
+	if !breaker.Open() {
+		t.Fatal("Open() = false after 2 failures")
+	}
+	wsHostv2 = accepting
+	if err := prepare(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Prepare() while open error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if n := len(server.requests()); n != 0 {
+		t.Errorf("the endpoint got %d requests while the breaker was open", n)
+	}
+
+	// the probe after the cooldown fails: open for another cooldown
+	time.Sleep(cooldown)
+	wsHostv2 = refusedHost(t)
+	if err := prepare(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want the dial refused", err)
+	}
+	if err := prepare(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Prepare() after a failed probe error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// the next probe succeeds: closed
+	time.Sleep(cooldown)
+	wsHostv2 = accepting
+	if err := prepare(); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if breaker.Open() {
+		t.Error("Open() = true after a successful probe")
+	}
+	if err := prepare(); err != nil {
+		t.Errorf("Prepare() once closed error = %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresRejectedRequests(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4002,"message":"auth failed"}`))
+		drain(conn)
+	})
+	breaker := NewCircuitBreaker(1, time.Minute)
+	for i := 0; i < 3; i++ {
+		s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithCircuitBreaker(breaker))
+		if err := s.Prepare(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Prepare() #%d error = %v, want the auth failure", i+1, err)
+		}
+	}
+	if breaker.Open() {
+		t.Error("Open() = true, want rejected requests not counted")
+	}
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Concatenator joins the audio of several sessions into W, e.g. to build a narration out
+// of separate syntheses, separated by Gap of silence. Use it as AudioWriter of each
+// session in turn, then call EndSegment with the subtitles of the session once Wait
+// returned: Subtitles returns them all on the timeline of the joined audio. The sessions
+// must produce 16-bit mono pcm at SampleRate, no Transcoder changing it, as the silence
+// and the offsets are computed for it; other codecs or rates make a corrupt stream.
+// BeginIndex and EndIndex are left relative to the text of their session.
+type Concatenator struct {
+	W          io.Writer
+	SampleRate int64
+	Gap        time.Duration
+
+	mutex     sync.Mutex
+	written   int64 // bytes written to W
+	segStart  int64 // offset of the current segment in W, once written to
+	segOpen   bool  // the current segment was written to
+	needGap   bool  // a segment was written, the next one starts with the gap
+	subtitles []Synthesisv2Subtitle
+}
+
+// NewConcatenator creates instance of Concatenator
+func NewConcatenator(w io.Writer, sampleRate int64, gap time.Duration) *Concatenator {
+	return &Concatenator{W: w, SampleRate: sampleRate, Gap: gap}
+}
+
+// Write writes pcm of the current segment, preceded by the gap on the first write of a
+// segment following another
+func (c *Concatenator) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.segOpen {
+		if c.needGap {
+			n, err := c.W.Write(make([]byte, c.bytesOf(c.Gap)))
+			c.written += int64(n)
+			if err != nil {
+				return 0, err
+			}
+		}
+		c.segStart = c.written
+		c.segOpen = true
+	}
+	n, err := c.W.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// EndSegment ends the current segment, adding subs shifted by the start of the segment
+// to Subtitles. A segment without audio adds no gap.
+func (c *Concatenator) EndSegment(subs []Synthesisv2Subtitle) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	start := c.segStart
+	if !c.segOpen {
+		start = c.written
+		if c.needGap {
+			start += int64(c.bytesOf(c.Gap))
+		}
+	}
+	offset := int64(c.durationOf(start) / time.Millisecond)
+	for _, sub := range subs {
+		sub.BeginTime += offset
+		sub.EndTime += offset
+		c.subtitles = append(c.subtitles, sub)
+	}
+	c.needGap = c.needGap || c.segOpen
+	c.segOpen = false
+}
+
+// Subtitles returns the subtitles of the ended segments on the joined timeline
+func (c *Concatenator) Subtitles() []Synthesisv2Subtitle {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]Synthesisv2Subtitle(nil), c.subtitles...)
+}
+
+// Duration returns the duration of the audio written so far, gaps included
+func (c *Concatenator) Duration() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.durationOf(c.written)
+}
+
+func (c *Concatenator) bytesOf(d time.Duration) int {
+	return int(int64(d) * c.SampleRate / int64(time.Second) * 2)
+}
+
+func (c *Concatenator) durationOf(n int64) time.Duration {
+	if c.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(c.SampleRate*2)
+}
@@ -0,0 +1,60 @@
+package tts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestConcatenatorJoinsSessions(t *testing.T) {
+	var out bytes.Buffer
+	c := NewConcatenator(&out, 16000, 50*time.Millisecond)
+	clips := [][]byte{pcm(3200), pcm(1600)} // 100 ms and 50 ms
+	for i, clip := range clips {
+		conn := newFakeConn()
+		s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+		s.EnableSubtitle = true
+		s.AudioWriter = c
+		startFake(s, conn)
+		conn.binary(clip)
+		conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"%d","BeginIndex":0,"EndIndex":1,"BeginTime":10,"EndTime":40}]}}`, i)
+		conn.final()
+		if err := s.Wait(); err != nil {
+			t.Fatalf("session %d: Wait() error = %v", i, err)
+		}
+		c.EndSegment(s.Subtitles())
+	}
+
+	want := append(append(pcm(3200), make([]byte, 1600)...), pcm(1600)...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("joined audio is %d bytes, want %d: the clips separated by 50 ms of silence", out.Len(), len(want))
+	}
+	if d := c.Duration(); d != 200*time.Millisecond {
+		t.Errorf("Duration() = %v, want 200ms", d)
+	}
+	subs := c.Subtitles()
+	if len(subs) != 2 {
+		t.Fatalf("Subtitles() = %+v, want one per session", subs)
+	}
+	if subs[0].BeginTime != 10 || subs[0].EndTime != 40 {
+		t.Errorf("first subtitle at %d-%d ms, want 10-40", subs[0].BeginTime, subs[0].EndTime)
+	}
+	// after the first clip and the gap
+	if subs[1].BeginTime != 160 || subs[1].EndTime != 190 || subs[1].BeginIndex != 0 {
+		t.Errorf("second subtitle at %d-%d ms index %d, want 160-190 index 0", subs[1].BeginTime, subs[1].EndTime, subs[1].BeginIndex)
+	}
+}
+
+func TestConcatenatorEmptySegment(t *testing.T) {
+	var out bytes.Buffer
+	c := NewConcatenator(&out, 16000, 50*time.Millisecond)
+	c.Write(pcm(320))
+	c.EndSegment(nil)
+	c.EndSegment([]Synthesisv2Subtitle{{Text: "x", BeginTime: 0, EndTime: 5}})
+	c.Write(pcm(320))
+	c.EndSegment(nil)
+	// the empty segment adds no gap of its own
+	if want := 320 + 1600 + 320; out.Len() != want {
+		t.Errorf("joined audio is %d bytes, want %d", out.Len(), want)
+	}
+}
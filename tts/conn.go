@@ -0,0 +1,156 @@
+package tts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn is the part of *websocket.Conn used once a session is started
+type wsConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteJSON(v interface{}) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// CapturedFrame is one inbound frame of a capture file.
+//
+// A capture file holds one JSON object per line, in arrival order:
+//
+//	{"opcode":2,"time":"2020-10-16T10:00:00.123456789+08:00","data":"<base64 payload>"}
+//
+// opcode is the websocket message type (1 text, 2 binary). Only frames received after
+// the ready frame are recorded.
+type CapturedFrame struct {
+	OpCode int       `json:"opcode"`
+	Time   time.Time `json:"time"`
+	Data   []byte    `json:"data"`
+}
+
+// ReadFrameCapture loads the frames recorded by WithFrameCapture
+func ReadFrameCapture(path string) ([]CapturedFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var frames []CapturedFrame
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		frame := CapturedFrame{}
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err.Error())
+		}
+		frames = append(frames, frame)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// captureConn records every frame read from conn to a capture file
+type captureConn struct {
+	wsConn
+	mutex sync.Mutex
+	file  *os.File
+	enc   *json.Encoder
+}
+
+func newCaptureConn(conn wsConn, path string) (*captureConn, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &captureConn{wsConn: conn, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (c *captureConn) ReadMessage() (int, []byte, error) {
+	opCode, data, err := c.wsConn.ReadMessage()
+	if err == nil {
+		c.mutex.Lock()
+		if c.file != nil {
+			c.enc.Encode(CapturedFrame{OpCode: opCode, Time: time.Now(), Data: data})
+		}
+		c.mutex.Unlock()
+	}
+	return opCode, data, err
+}
+
+func (c *captureConn) Close() error {
+	err := c.wsConn.Close()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file != nil {
+		if ferr := c.file.Close(); err == nil {
+			err = ferr
+		}
+		c.file = nil
+	}
+	return err
+}
+
+var errReplayConnClosed = errors.New("replay connection closed")
+
+// replayConn serves captured frames in order and discards writes. Once the frames
+// are exhausted it reports a normal websocket closure.
+type replayConn struct {
+	mutex  sync.Mutex
+	frames []CapturedFrame
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newReplayConn(frames []CapturedFrame) *replayConn {
+	return &replayConn{frames: frames, closed: make(chan struct{})}
+}
+
+func (c *replayConn) ReadMessage() (int, []byte, error) {
+	select {
+	case <-c.closed:
+		return 0, nil, errReplayConnClosed
+	default:
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.frames) == 0 {
+		return 0, nil, &websocket.CloseError{Code: websocket.CloseNormalClosure}
+	}
+	frame := c.frames[0]
+	c.frames = c.frames[1:]
+	return frame.OpCode, frame.Data, nil
+}
+
+func (c *replayConn) WriteJSON(v interface{}) error {
+	select {
+	case <-c.closed:
+		return errReplayConnClosed
+	default:
+		return nil
+	}
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
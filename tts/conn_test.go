@@ -0,0 +1,57 @@
+package tts
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFrameCaptureReplay(t *testing.T) {
+	subtitle := `{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginTime":0,"EndTime":160}]}}`
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		conn.WriteMessage(websocket.BinaryMessage, pcm(640))
+		conn.WriteMessage(websocket.TextMessage, []byte(subtitle))
+		conn.WriteMessage(websocket.BinaryMessage, pcm(320))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+		drain(conn)
+	})
+	path := filepath.Join(t.TempDir(), "session.frames")
+	live := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, live, WithFrameCapture(path))
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	frames, err := ReadFrameCapture(path)
+	if err != nil {
+		t.Fatalf("ReadFrameCapture() error = %v", err)
+	}
+	if len(frames) != 4 || frames[0].OpCode != websocket.BinaryMessage || frames[1].OpCode != websocket.TextMessage {
+		t.Fatalf("captured %d frames %+v, want the 4 frames after ready", len(frames), frames)
+	}
+	if string(frames[1].Data) != subtitle || frames[0].Time.IsZero() {
+		t.Errorf("captured frame %+v, want the subtitle frame as sent", frames[1])
+	}
+
+	replayed := &recordListener{}
+	r := NewSpeechWsv2Synthesizer(0, nil, replayed)
+	if err := r.ReplayFrames(path); err != nil {
+		t.Fatalf("ReplayFrames() error = %v", err)
+	}
+	if err := r.Wait(); err != nil {
+		t.Fatalf("Wait() after ReplayFrames() error = %v", err)
+	}
+	if got, want := replayed.eventList(), live.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed events %v, want %v", got, want)
+	}
+	if !bytes.Equal(replayed.audioBytes(), live.audioBytes()) {
+		t.Error("replayed audio differs from the live session")
+	}
+}
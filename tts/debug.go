@@ -0,0 +1,39 @@
+package tts
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// debugRecord is a line written to DebugFunc with WithJSONDebug
+type debugRecord struct {
+	Event     string `json:"event"`
+	SessionId string `json:"session_id"`
+	RequestId string `json:"request_id"`
+	Bytes     int    `json:"bytes"`
+	Ts        string `json:"ts"`
+	Message   string `json:"message"`
+}
+
+// debug passes message to DebugFunc when Debug is set, as a JSON line with WithJSONDebug
+func (synthesizer *SpeechWsv2Synthesizer) debug(event string, bytes int, message string) {
+	if !synthesizer.Debug || synthesizer.DebugFunc == nil {
+		return
+	}
+	if !synthesizer.jsonDebug {
+		synthesizer.DebugFunc(message)
+		return
+	}
+	synthesizer.statusMutex.Lock()
+	requestId := synthesizer.requestId
+	synthesizer.statusMutex.Unlock()
+	line, _ := json.Marshal(debugRecord{
+		Event:     event,
+		SessionId: synthesizer.SessionId,
+		RequestId: requestId,
+		Bytes:     bytes,
+		Ts:        time.Now().Format(time.RFC3339Nano),
+		Message:   message,
+	})
+	synthesizer.DebugFunc(string(line))
+}
@@ -0,0 +1,89 @@
+package tts
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// debugLines collects the DebugFunc output
+type debugLines struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (d *debugLines) add(line string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.lines = append(d.lines, line)
+}
+
+func (d *debugLines) all() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string(nil), d.lines...)
+}
+
+func TestJSONDebugLines(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, pcm(320))
+	})
+	out := &debugLines{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithJSONDebug())
+	s.Debug = true
+	s.DebugFunc = out.add
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if err := s.Send("你好"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	events := map[string]bool{}
+	for _, line := range out.all() {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("debug line %q is not JSON: %v", line, err)
+		}
+		for _, key := range []string{"event", "session_id", "request_id", "bytes", "ts", "message"} {
+			if _, ok := record[key]; !ok {
+				t.Errorf("debug line %q lacks %q", line, key)
+			}
+		}
+		if record["session_id"] != s.SessionId {
+			t.Errorf("session_id = %v, want %q", record["session_id"], s.SessionId)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, record["ts"].(string)); err != nil {
+			t.Errorf("ts %v: %v", record["ts"], err)
+		}
+		events[record["event"].(string)] = true
+		if record["event"] == "frame" && record["request_id"] != "test-request" {
+			t.Errorf("frame line %q, want request_id test-request", line)
+		}
+	}
+	for _, event := range []string{"sign", "dial", "frame"} {
+		if !events[event] {
+			t.Errorf("no %q debug line in %q", event, out.all())
+		}
+	}
+}
+
+func TestPlainDebugByDefault(t *testing.T) {
+	out := &debugLines{}
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.Debug = true
+	s.DebugFunc = out.add
+	s.debug("frame", 3, "raw text")
+	if got := out.all(); len(got) != 1 || got[0] != "raw text" {
+		t.Errorf("DebugFunc got %q, want the plain message", got)
+	}
+}
@@ -0,0 +1,126 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidFrame is wrapped by the errors of WithStrictDecoding
+var ErrInvalidFrame = errors.New("invalid frame")
+
+// strictDecoding is set by WithStrictDecoding
+type strictDecoding struct {
+	disallowUnknownFields bool
+}
+
+// incompleteJSON reports whether data is the beginning of a JSON value cut short
+func incompleteJSON(data []byte) bool {
+	var value json.RawMessage
+	return json.NewDecoder(bytes.NewReader(data)).Decode(&value) == io.ErrUnexpectedEOF
+}
+
+// decodeResponse decodes a text frame. Without WithStrictDecoding it behaves as
+// json.Unmarshal: missing fields are left to their zero value.
+func (synthesizer *SpeechWsv2Synthesizer) decodeResponse(data []byte) (*SpeechWsv2SynthesisResponse, error) {
+	msg := SpeechWsv2SynthesisResponse{}
+	strict := synthesizer.strict
+	if strict == nil {
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return &msg, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&msg); err != nil {
+		return nil, describeDecodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, fmt.Errorf("%w: trailing data after the JSON object", ErrInvalidFrame)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, describeDecodeError(err)
+	}
+	if err := validateResponse(&msg, fields); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// validateResponse checks the fields required by the kind of frame: every frame carries
+// code and session_id, a successful frame that is neither ready, heartbeat nor final
+// carries result, and subtitles must not end before they begin.
+func validateResponse(msg *SpeechWsv2SynthesisResponse, fields map[string]json.RawMessage) error {
+	required := []string{"code", "session_id"}
+	if msg.Code == 0 && msg.Ready != 1 && msg.Heartbeat != 1 && msg.Final != 1 {
+		required = append(required, "result")
+	}
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%w: missing field %s", ErrInvalidFrame, name)
+		}
+	}
+	for i, sub := range msg.Result.Subtitles {
+		if sub.EndTime < sub.BeginTime {
+			return fmt.Errorf("%w: result.subtitles[%d] ends at %dms before it begins at %dms",
+				ErrInvalidFrame, i, sub.EndTime, sub.BeginTime)
+		}
+		if sub.EndIndex < sub.BeginIndex {
+			return fmt.Errorf("%w: result.subtitles[%d] EndIndex %d is before BeginIndex %d",
+				ErrInvalidFrame, i, sub.EndIndex, sub.BeginIndex)
+		}
+	}
+	return nil
+}
+
+func describeDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%w: field %s is a JSON %s, want %s", ErrInvalidFrame, typeErr.Field, typeErr.Value, typeErr.Type)
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%w: malformed JSON at offset %d: %s", ErrInvalidFrame, syntaxErr.Offset, err.Error())
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidFrame, err.Error())
+	}
+}
+
+// ErrUnsupportedProtocol is wrapped in the SynthesisError returned by Prepare when the
+// handshake announces a protocol version other than 2
+var ErrUnsupportedProtocol = errors.New("unsupported server protocol version")
+
+// supportedProtocolVersions lists the versions this client speaks, a handshake without
+// version is taken as version 2
+var supportedProtocolVersions = map[string]bool{"": true, "2": true}
+
+// checkProtocolVersion reads the version or protocol_version field of the handshake
+// response, as a number or a string optionally prefixed with "v"
+func checkProtocolVersion(data []byte) error {
+	var handshake struct {
+		Version         json.RawMessage `json:"version"`
+		ProtocolVersion json.RawMessage `json:"protocol_version"`
+	}
+	if err := json.Unmarshal(data, &handshake); err != nil {
+		return err
+	}
+	for _, raw := range []json.RawMessage{handshake.Version, handshake.ProtocolVersion} {
+		version := strings.TrimSpace(string(raw))
+		if version == "null" {
+			version = ""
+		}
+		version = strings.TrimPrefix(strings.ToLower(strings.Trim(version, `"`)), "v")
+		version = strings.TrimSuffix(version, ".0")
+		if !supportedProtocolVersions[version] {
+			return fmt.Errorf("%w %s, this client speaks version 2", ErrUnsupportedProtocol, raw)
+		}
+	}
+	return nil
+}
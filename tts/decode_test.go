@@ -0,0 +1,205 @@
+package tts
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStrictDecodingRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		unknown bool
+		wantMsg string
+	}{
+		{"code of the wrong type", `{"code":"0","session_id":"s","final":1}`, false, "field code is a JSON string, want int"},
+		{"missing session_id", `{"code":0,"final":1}`, false, "missing field session_id"},
+		{"missing result", `{"code":0,"session_id":"s"}`, false, "missing field result"},
+		{"subtitle ending first", `{"code":0,"session_id":"s","result":{"subtitles":[{"BeginTime":200,"EndTime":100}]}}`, false, "ends at 100ms before it begins at 200ms"},
+		{"trailing data", `{"code":0,"session_id":"s","final":1} {}`, false, "trailing data"},
+		{"unknown field", `{"code":0,"session_id":"s","final":1,"extra":true}`, true, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSpeechWsv2Synthesizer(0, nil, nil, WithStrictDecoding(tt.unknown))
+			_, err := s.decodeResponse([]byte(tt.frame))
+			if !errors.Is(err, ErrInvalidFrame) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("decodeResponse(%s) error = %v, want %v mentioning %q", tt.frame, err, ErrInvalidFrame, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestStrictDecodingAccepts(t *testing.T) {
+	frames := []string{
+		`{"code":0,"session_id":"s","ready":1}`,
+		`{"code":0,"session_id":"s","final":1}`,
+		`{"code":0,"session_id":"s","result":{"subtitles":[{"Text":"a","BeginTime":0,"EndTime":100}]}}`,
+		`{"code":10001,"session_id":"s","message":"bad request"}`,
+		`{"code":0,"session_id":"s","final":1,"extra":true}`,
+	}
+	s := NewSpeechWsv2Synthesizer(0, nil, nil, WithStrictDecoding(false))
+	for _, frame := range frames {
+		if _, err := s.decodeResponse([]byte(frame)); err != nil {
+			t.Errorf("decodeResponse(%s) error = %v", frame, err)
+		}
+	}
+}
+
+func TestStrictDecodingFailsSession(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithStrictDecoding(false))
+	startFake(s, conn)
+	conn.text(`{"code":"0","session_id":"test-session","final":1}`)
+	s.Wait()
+	if failures := listener.failures(); len(failures) != 1 || !errors.Is(failures[0], ErrInvalidFrame) ||
+		!strings.Contains(failures[0].Error(), "field code") {
+		t.Errorf("OnSynthesisFail got %v, want the invalid code reported", failures)
+	}
+}
+
+func TestUnsupportedProtocolVersion(t *testing.T) {
+	closed := make(chan struct{})
+	mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","version":"v3"}`))
+		drain(conn)
+		close(closed)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	err := s.Prepare()
+	if !errors.Is(err, ErrUnsupportedProtocol) || !strings.Contains(err.Error(), `"v3"`) {
+		t.Fatalf("Prepare() = %v, want %v naming the version", err, ErrUnsupportedProtocol)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("the connection was not closed")
+	}
+}
+
+func TestCheckProtocolVersion(t *testing.T) {
+	tests := []struct {
+		handshake string
+		wantErr   bool
+	}{
+		{`{"code":0}`, false},
+		{`{"code":0,"version":2}`, false},
+		{`{"code":0,"version":"v2"}`, false},
+		{`{"code":0,"protocol_version":"2.0"}`, false},
+		{`{"code":0,"version":null}`, false},
+		{`{"code":0,"version":3}`, true},
+		{`{"code":0,"protocol_version":"v1"}`, true},
+	}
+	for _, tt := range tests {
+		if err := checkProtocolVersion([]byte(tt.handshake)); (err != nil) != tt.wantErr {
+			t.Errorf("checkProtocolVersion(%s) error = %v, wantErr %v", tt.handshake, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIncompleteJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`{"code":0}`, false},
+		{`{"code":0,"mess`, true},
+		{`{"result":{"subtitles":[`, true},
+		{`{"code":0}}`, false},
+		{`not json`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := incompleteJSON([]byte(tt.data)); got != tt.want {
+			t.Errorf("incompleteJSON(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestFragmentedTextFrame(t *testing.T) {
+	frame := `{"code":0,"message":"success","result":{"subtitles":[{"Text":"你好","BeginIndex":0,"EndIndex":2,"BeginTime":0,"EndTime":320}]}}`
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	// split as a misbehaving proxy may, once in the middle of a rune
+	for _, cut := range [][2]int{{0, 20}, {20, 67}, {67, len(frame)}} {
+		conn.frames <- fakeFrame{op: websocket.TextMessage, data: []byte(frame[cut[0]:cut[1]])}
+	}
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Fatalf("OnSynthesisFail called with %v, want the fragments reassembled", failures)
+	}
+	listener.mutex.Lock()
+	texts := listener.texts
+	listener.mutex.Unlock()
+	if len(texts) != 1 || len(texts[0].Result.Subtitles) != 1 || texts[0].Result.Subtitles[0].Text != "你好" {
+		t.Errorf("OnTextResult got %d frames, want the reassembled one", len(texts))
+	}
+}
+
+// unknownFrameListener records the frames of unexpected message types
+type unknownFrameListener struct {
+	recordListener
+	ops []int
+}
+
+func (l *unknownFrameListener) OnUnknownFrame(messageType int, data []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.ops = append(l.ops, messageType)
+	l.record("unknown %d", len(data))
+}
+
+func TestUnknownFrame(t *testing.T) {
+	conn := newFakeConn()
+	listener := &unknownFrameListener{}
+	var logged []string
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.Debug = true
+	s.DebugFunc = func(message string) { logged = append(logged, message) }
+	startFake(s, conn)
+	conn.frames <- fakeFrame{op: websocket.PingMessage, data: []byte("ping")}
+	conn.binary(pcm(320))
+	conn.frames <- fakeFrame{op: 42}
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []string{"start", "unknown 4", "audio 320", "unknown 0", "end"}
+	if got := listener.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(listener.ops, []int{websocket.PingMessage, 42}) {
+		t.Errorf("OnUnknownFrame message types = %v, want [%d 42]", listener.ops, websocket.PingMessage)
+	}
+	if len(logged) == 0 || !strings.Contains(strings.Join(logged, "\n"), "unknown message type 42") {
+		t.Errorf("debug output %q doesn't log the unknown frames", logged)
+	}
+}
+
+func TestUnknownFrameWithoutListener(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.frames <- fakeFrame{op: websocket.PongMessage}
+	conn.binary(pcm(320))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []string{"start", "audio 320", "end"}
+	if got := listener.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
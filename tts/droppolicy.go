@@ -0,0 +1,36 @@
+package tts
+
+import "sync/atomic"
+
+// DropPolicy decides what happens to an event when the listener falls behind and the
+// event queue is full. Audio, start, end, failure and OnSubtitleAppended events are
+// never dropped, whatever the policy.
+type DropPolicy int
+
+const (
+	// DropNone blocks the reading of frames until the listener catches up, the default.
+	// Every wait is counted in Stats.Overflowed.
+	DropNone DropPolicy = iota
+	// DropText discards OnTextResult and OnReady events that find the queue full, counted
+	// in Stats.Dropped. Audio keeps blocking as with DropNone.
+	DropText
+)
+
+// emit queues e for eventDispatch according to the DropPolicy
+func (synthesizer *SpeechWsv2Synthesizer) emit(e speechWsSynthesisEventv2) {
+	if synthesizer.queue != nil {
+		synthesizer.emitQueued(e)
+		return
+	}
+	select {
+	case synthesizer.eventChan <- e:
+		return
+	default:
+	}
+	if synthesizer.dropPolicy == DropText && (e.t == eventTypeWsTextResultv2 || e.t == eventTypeWsReadyv2) {
+		atomic.AddInt64(&synthesizer.counters.dropped, 1)
+		return
+	}
+	atomic.AddInt64(&synthesizer.counters.overflowed, 1)
+	synthesizer.eventChan <- e
+}
@@ -0,0 +1,82 @@
+package tts
+
+import "testing"
+
+// blockingListener blocks the first OnAudioResult until release is closed, signalling
+// blocked once it is
+type blockingListener struct {
+	recordListener
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func newBlockingListener() *blockingListener {
+	return &blockingListener{blocked: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (l *blockingListener) OnAudioResult(data []byte) {
+	select {
+	case <-l.blocked:
+	default:
+		close(l.blocked)
+		<-l.release
+	}
+	l.recordListener.OnAudioResult(data)
+}
+
+const textFrame = `{"code":0,"message":"success","result":{"subtitles":[{"Text":"a","BeginTime":0,"EndTime":10}]}}`
+
+func TestDropTextKeepsAudio(t *testing.T) {
+	conn := newFakeConn()
+	listener := newBlockingListener()
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithDropPolicy(DropText))
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	<-listener.blocked
+	// 10 text events fill the queue, the next 5 are dropped, then audio waits for room
+	for i := 0; i < 15; i++ {
+		conn.text(textFrame)
+	}
+	conn.binary(pcm(320))
+	conn.binary(pcm(320))
+	waitFor(t, "overflow", func() bool { return s.Stats().Overflowed == 1 })
+	if stats := s.Stats(); stats.Dropped != 5 {
+		t.Errorf("Dropped = %d, want the 5 text events finding the queue full", stats.Dropped)
+	}
+	close(listener.release)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := listener.count("audio"); n != 3 {
+		t.Errorf("OnAudioResult called %d times, want all 3 audio frames", n)
+	}
+	if n := listener.count("text"); n != 10 {
+		t.Errorf("OnTextResult called %d times, want the 10 text events queued", n)
+	}
+}
+
+func TestDropNoneCountsOverflow(t *testing.T) {
+	conn := newFakeConn()
+	listener := newBlockingListener()
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	<-listener.blocked
+	for i := 0; i < 12; i++ {
+		conn.text(textFrame)
+	}
+	waitFor(t, "overflow", func() bool { return s.Stats().Overflowed == 1 })
+	close(listener.release)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	// the reading waited once, for the 11th event, the 12th found room
+	if stats := s.Stats(); stats.Dropped != 0 || stats.Overflowed != 1 {
+		t.Errorf("Dropped, Overflowed = %d, %d, want 0, 1", stats.Dropped, stats.Overflowed)
+	}
+	if n := listener.count("text"); n != 12 {
+		t.Errorf("OnTextResult called %d times, want all 12", n)
+	}
+}
@@ -0,0 +1,55 @@
+package tts
+
+import "fmt"
+
+// Emotion categories, the voices supporting them list them in VoiceInfo.Emotions
+const (
+	EmotionNeutral = "neutral"
+	EmotionSad     = "sad"
+	EmotionHappy   = "happy"
+	EmotionAngry   = "angry"
+	EmotionFear    = "fear"
+	EmotionNews    = "news"
+	EmotionStory   = "story"
+	EmotionRadio   = "radio"
+	EmotionPoetry  = "poetry"
+	EmotionCall    = "call"
+)
+
+var emotionCategories = []string{EmotionNeutral, EmotionSad, EmotionHappy, EmotionAngry, EmotionFear,
+	EmotionNews, EmotionStory, EmotionRadio, EmotionPoetry, EmotionCall}
+
+// Emotion is the emotion of a synthesis, see WithEmotion
+type Emotion struct {
+	Category  string // one of the Emotion constants
+	Intensity int64  // 50 to 200, 100 being neutral, zero lets the server use 100
+}
+
+// Validate checks Category is one of the Emotion constants and Intensity is in range
+func (e Emotion) Validate() error {
+	known := false
+	for _, category := range emotionCategories {
+		known = known || category == e.Category
+	}
+	if !known {
+		return fmt.Errorf("unknown emotion category %q, see the Emotion constants", e.Category)
+	}
+	if e.Intensity != 0 && (e.Intensity < 50 || e.Intensity > 200) {
+		return fmt.Errorf("emotion intensity %d out of range [50, 200]", e.Intensity)
+	}
+	return nil
+}
+
+// checkEmotion checks the VoiceType supports category, unless WithoutVoiceValidation is set
+func (synthesizer *SpeechWsv2Synthesizer) checkEmotion(category string) error {
+	if synthesizer.skipVoiceValidation {
+		return nil
+	}
+	voice, _ := LookupVoice(synthesizer.VoiceType)
+	for _, e := range voice.Emotions {
+		if e == category {
+			return nil
+		}
+	}
+	return fmt.Errorf("emotion %q not supported by VoiceType %d", category, synthesizer.VoiceType)
+}
@@ -0,0 +1,111 @@
+package tts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSendWithEmotionEncodesFrame(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.VoiceType = 101001
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.SendWithEmotion("太好了！", "happy", 150); err != nil {
+		t.Fatalf("SendWithEmotion() error = %v", err)
+	}
+	if err := s.Send("然后呢"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	sent := conn.sent()
+	if len(sent) != 2 {
+		t.Fatalf("%d frames written, want 2", len(sent))
+	}
+	if sent[0]["data"] != "太好了！" || sent[0]["emotion_category"] != "happy" || sent[0]["emotion_intensity"] != float64(150) {
+		t.Errorf("SendWithEmotion() frame = %v, want the emotion of the chunk", sent[0])
+	}
+	if _, ok := sent[1]["emotion_category"]; ok {
+		t.Errorf("Send() frame = %v, want no emotion after SendWithEmotion", sent[1])
+	}
+}
+
+func TestSendWithEmotionInvalid(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.VoiceType = 101001
+	startFake(s, conn)
+	defer s.Abort()
+	for _, intensity := range []int64{49, 201} {
+		if err := s.SendWithEmotion("好", "happy", intensity); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("SendWithEmotion() with intensity %d error = %v, want out of range", intensity, err)
+		}
+	}
+	if err := s.SendWithEmotion("好", "furious", 100); err == nil {
+		t.Error("SendWithEmotion() with an unknown category error = nil")
+	}
+	if n := len(conn.sent()); n != 0 {
+		t.Errorf("%d frames written, want none", n)
+	}
+}
+
+func TestSendWithEmotionUnknownCategoryWithoutVoiceValidation(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithoutVoiceValidation())
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.SendWithEmotion("好", "furious", 100); err == nil || !strings.Contains(err.Error(), "unknown emotion category") {
+		t.Errorf("SendWithEmotion() with an unknown category error = %v, want it rejected", err)
+	}
+	if n := len(conn.sent()); n != 0 {
+		t.Errorf("%d frames written, want none", n)
+	}
+	// the voice check is still skipped for a known category
+	if err := s.SendWithEmotion("好", "sad", 100); err != nil {
+		t.Errorf("SendWithEmotion() error = %v", err)
+	}
+}
+
+func TestEmotionValidate(t *testing.T) {
+	tests := []struct {
+		emotion Emotion
+		wantErr string
+	}{
+		{Emotion{Category: EmotionHappy, Intensity: 150}, ""},
+		{Emotion{Category: EmotionSad}, ""},
+		{Emotion{Category: EmotionCall, Intensity: 50}, ""},
+		{Emotion{Category: EmotionNews, Intensity: 200}, ""},
+		{Emotion{Category: "furious", Intensity: 100}, "unknown emotion category"},
+		{Emotion{Intensity: 100}, "unknown emotion category"},
+		{Emotion{Category: EmotionHappy, Intensity: 49}, "out of range"},
+		{Emotion{Category: EmotionHappy, Intensity: 201}, "out of range"},
+		{Emotion{Category: EmotionHappy, Intensity: -1}, "out of range"},
+	}
+	for _, tt := range tests {
+		err := tt.emotion.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%+v.Validate() error = %v", tt.emotion, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%+v.Validate() error = %v, want %q", tt.emotion, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWithEmotionValidatedBeforeDialing(t *testing.T) {
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{},
+		WithEmotion(Emotion{Category: EmotionHappy, Intensity: 300}))
+	s.VoiceType = 101001
+	if err := s.Prepare(); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Prepare() error = %v, want the intensity rejected", err)
+	}
+
+	s = NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{},
+		WithEmotion(Emotion{Category: EmotionHappy, Intensity: 120}))
+	s.VoiceType = 101001
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if s.EmotionCategory != EmotionHappy || s.EmotionIntensity != 120 {
+		t.Errorf("WithEmotion set %q %d, want happy 120", s.EmotionCategory, s.EmotionIntensity)
+	}
+}
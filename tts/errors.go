@@ -0,0 +1,180 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrPrepareTimeout is returned by Prepare when PrepareTimeout expires
+var ErrPrepareTimeout = errors.New("prepare timeout")
+
+// ErrDrainTimeout is returned by Wait when the final frame did not arrive within the drain
+// timeout following Complete
+var ErrDrainTimeout = errors.New("drain timeout")
+
+// ErrSessionDuration is returned by Wait when the session was closed on reaching
+// MaxSessionDuration
+var ErrSessionDuration = errors.New("max session duration reached")
+
+// ErrAudioLimitReached is returned by Wait when the session was closed on reaching
+// MaxAudioBytes, the audio up to the limit was delivered
+var ErrAudioLimitReached = errors.New("max audio bytes reached")
+
+// ErrDone is returned by Wait when the session was closed by the channel of
+// WithDoneChannel
+var ErrDone = errors.New("done channel closed")
+
+// ErrIncomplete is returned by Wait, with WithRequireFinal, when the session ended
+// without the final frame
+var ErrIncomplete = errors.New("session ended before the final frame")
+
+// ErrIdleTimeout is reported to OnSynthesisFail when no frame arrived within IdleTimeout
+var ErrIdleTimeout = errors.New("idle timeout")
+
+// ErrWriteTimeout is returned by Send and Complete when a write exceeds WriteTimeout
+var ErrWriteTimeout = errors.New("write timeout")
+
+// ErrNoText is returned by Complete when no text was sent
+var ErrNoText = errors.New("no text to synthesize")
+
+// ErrChunkTooLong is returned by Send when a chunk exceeds MaxChunkChars
+var ErrChunkTooLong = errors.New("chunk too long")
+
+// ErrTextTooLong is returned by Prepare when Text is too long for the request URL
+var ErrTextTooLong = errors.New("text too long for the request URL")
+
+// ErrCircuitOpen is returned by Prepare, without connecting, while the CircuitBreaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ErrThrottled matches, with errors.Is, the SynthesisError of a request rejected with code
+// 4006 because the account exceeded its concurrency or QPS limit. Back off before retrying.
+var ErrThrottled = errors.New("throttled")
+
+// ErrAuthFailed matches, with errors.Is, the SynthesisError of a request rejected with code
+// 4002 because the credential or the signature is invalid
+var ErrAuthFailed = errors.New("authentication failed")
+
+// ErrSignatureExpired matches, with errors.Is, the SynthesisError of a request rejected
+// with code 4002 because its Expired timestamp is in the past, the clock of the host being
+// behind or a signed URL reused too late. Check the clock, or sign again. It also matches
+// ErrAuthFailed.
+var ErrSignatureExpired = errors.New("signature expired")
+
+// codeThrottled is the server code of ErrThrottled
+const codeThrottled = 4006
+
+// codeAuthFailed is the server code of ErrAuthFailed
+const codeAuthFailed = 4002
+
+// signatureRejected reports whether Prepare failed on a rejected signature, or timed out
+// waiting for the ready frame as some gateways do instead of answering 4002
+func signatureRejected(err error) bool {
+	var synthesisErr *SynthesisError
+	if errors.As(err, &synthesisErr) && synthesisErr.Err == nil {
+		return synthesisErr.Code == codeAuthFailed
+	}
+	return errors.Is(err, ErrPrepareTimeout)
+}
+
+// SynthesisError is the error of a failed session, reported to OnSynthesisFail or returned
+// by Prepare. Code and Message are set when the server answered with an error code, Err
+// when the connection failed.
+type SynthesisError struct {
+	SessionId string
+	Code      int
+	Message   string
+	Err       error
+}
+
+func (e *SynthesisError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("session_id: %s, code: %d, message: %s", e.SessionId, e.Code, e.Message)
+	}
+	return fmt.Sprintf("session_id: %s, error: %s", e.SessionId, e.Err.Error())
+}
+
+// Unwrap returns Err
+func (e *SynthesisError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e is an ErrThrottled, ErrAuthFailed or ErrSignatureExpired server error
+func (e *SynthesisError) Is(target error) bool {
+	if e.Err != nil {
+		return false
+	}
+	switch target {
+	case ErrThrottled:
+		return e.Code == codeThrottled
+	case ErrAuthFailed:
+		return e.Code == codeAuthFailed
+	case ErrSignatureExpired:
+		return e.Code == codeAuthFailed && signatureExpiredMessage(e.Message)
+	}
+	return false
+}
+
+// signatureExpiredMessage reports whether the message of a 4002 error is about the
+// Expired parameter, the server reporting it in English or Chinese
+func signatureExpiredMessage(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "expire") || strings.Contains(message, "过期")
+}
+
+// IsRetryable reports whether err is transient, so that the same request may succeed
+// when sent again, possibly after a backoff.
+//
+//	server code 4001-4005, 4007, 4010  no   invalid parameters, authentication, AppID not
+//	                                        activated, quota exhausted, account in arrears
+//	server code 4006                   yes  concurrency limit exceeded
+//	server code 4008, 4009             yes  session timed out or dropped
+//	server code >= 5000                yes  server side failure
+//	close 1001, 1006, 1011-1013        yes  going away, abnormal closure, server error,
+//	                                        service restart, try again later
+//	other close codes                  no
+//	ErrPrepareTimeout, ErrDrainTimeout yes
+//	ErrIdleTimeout                     yes
+//	net.Error, unexpected EOF          yes
+//
+// Any other error, including context cancellation, is not retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var synthesisErr *SynthesisError
+	if errors.As(err, &synthesisErr) && synthesisErr.Err == nil {
+		switch code := synthesisErr.Code; {
+		case code == codeThrottled, code == 4008, code == 4009:
+			return true
+		case code >= 5000:
+			return true
+		default:
+			return false
+		}
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseInternalServerErr,
+			websocket.CloseServiceRestart, websocket.CloseTryAgainLater:
+			return true
+		default:
+			return false
+		}
+	}
+	if errors.Is(err, ErrPrepareTimeout) || errors.Is(err, ErrDrainTimeout) || errors.Is(err, ErrIdleTimeout) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,125 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIsRetryable(t *testing.T) {
+	serverErr := func(code int) error { return &SynthesisError{SessionId: "s", Code: code} }
+	closeErr := func(code int) error { return &websocket.CloseError{Code: code} }
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"invalid parameter", serverErr(4001), false},
+		{"authentication", serverErr(4002), false},
+		{"quota exhausted", serverErr(4004), false},
+		{"arrears", serverErr(4010), false},
+		{"throttled", serverErr(4006), true},
+		{"session timed out", serverErr(4008), true},
+		{"server failure", serverErr(5000), true},
+		{"going away", closeErr(websocket.CloseGoingAway), true},
+		{"abnormal closure", closeErr(websocket.CloseAbnormalClosure), true},
+		{"try again later", closeErr(websocket.CloseTryAgainLater), true},
+		{"policy violation", closeErr(websocket.ClosePolicyViolation), false},
+		{"normal closure", closeErr(websocket.CloseNormalClosure), false},
+		{"prepare timeout", &SynthesisError{SessionId: "s", Err: ErrPrepareTimeout}, true},
+		{"drain timeout", ErrDrainTimeout, true},
+		{"idle timeout", fmt.Errorf("session_id: s, error: %w", ErrIdleTimeout), true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"net error", &SynthesisError{SessionId: "s", Err: timeoutError{}}, true},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottledInPrepare(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4006,"message":"concurrency exceeded"}`))
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	err := s.Prepare()
+	if !errors.Is(err, ErrThrottled) {
+		t.Fatalf("Prepare() = %v, want %v", err, ErrThrottled)
+	}
+	if errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Prepare() = %v matches %v", err, ErrAuthFailed)
+	}
+}
+
+func TestThrottledMidStream(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.text(`{"code":4006,"message":"concurrency exceeded"}`)
+	s.Wait()
+	if failures := listener.failures(); len(failures) != 1 || !errors.Is(failures[0], ErrThrottled) {
+		t.Errorf("OnSynthesisFail got %v, want %v", failures, ErrThrottled)
+	}
+}
+
+func TestThrottledOnlyForItsCode(t *testing.T) {
+	for _, err := range []error{
+		&SynthesisError{Code: 4005},
+		&SynthesisError{Code: 4006, Err: errors.New("dial failed")},
+	} {
+		if errors.Is(err, ErrThrottled) {
+			t.Errorf("errors.Is(%v, ErrThrottled) = true", err)
+		}
+	}
+}
+
+func TestSignatureExpiredInPrepare(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4002,"message":"signature expired"}`))
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	err := s.Prepare()
+	if !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("Prepare() = %v, want %v", err, ErrSignatureExpired)
+	}
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Prepare() = %v, want it to match %v too", err, ErrAuthFailed)
+	}
+	var synthesisErr *SynthesisError
+	if !errors.As(err, &synthesisErr) || synthesisErr.Code != 4002 {
+		t.Errorf("Prepare() = %v, want a SynthesisError of code 4002", err)
+	}
+}
+
+func TestSignatureExpiredMessages(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&SynthesisError{Code: 4002, Message: "Signature Expired"}, true},
+		{&SynthesisError{Code: 4002, Message: "签名已过期"}, true},
+		{&SynthesisError{Code: 4002, Message: "signature mismatch"}, false},
+		{&SynthesisError{Code: 4001, Message: "expired"}, false},
+		{&SynthesisError{Code: 4002, Message: "expired", Err: errors.New("dial failed")}, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, ErrSignatureExpired); got != tt.want {
+			t.Errorf("errors.Is(%v, ErrSignatureExpired) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tts
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// eventOverhead approximates the memory of an event besides its audio
+const eventOverhead = 256
+
+// eventQueue is the queue of WithEventQueue: it holds the events between receive and
+// eventDispatch, growing with the backlog up to limit bytes
+type eventQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	items  []speechWsSynthesisEventv2
+	bytes  int
+	limit  int
+	closed bool
+}
+
+func newEventQueue(limit int) *eventQueue {
+	q := &eventQueue{limit: limit}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+func eventSize(e speechWsSynthesisEventv2) int {
+	return len(e.d) + eventOverhead
+}
+
+// push queues e, waiting for room when the queue is over its limit
+func (q *eventQueue) push(e speechWsSynthesisEventv2) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	// an event larger than the limit is queued once the queue is empty
+	for len(q.items) > 0 && q.bytes+eventSize(e) > q.limit {
+		q.cond.Wait()
+	}
+	q.items = append(q.items, e)
+	q.bytes += eventSize(e)
+	q.cond.Broadcast()
+}
+
+// full reports whether e would have to wait for room
+func (q *eventQueue) full(e speechWsSynthesisEventv2) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.items) > 0 && q.bytes+eventSize(e) > q.limit
+}
+
+// close lets pump return once the queued events are passed on
+func (q *eventQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// pump passes the events on to out in order and closes out once the queue is closed and
+// empty
+func (q *eventQueue) pump(out chan<- speechWsSynthesisEventv2) {
+	defer close(out)
+	for {
+		q.mutex.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.items) == 0 {
+			q.mutex.Unlock()
+			return
+		}
+		e := q.items[0]
+		q.items[0] = speechWsSynthesisEventv2{}
+		q.items = q.items[1:]
+		q.bytes -= eventSize(e)
+		q.cond.Broadcast()
+		q.mutex.Unlock()
+		out <- e
+	}
+}
+
+// emitQueued is emit with WithEventQueue
+func (synthesizer *SpeechWsv2Synthesizer) emitQueued(e speechWsSynthesisEventv2) {
+	q := synthesizer.queue
+	if !q.full(e) {
+		q.push(e)
+		return
+	}
+	if synthesizer.dropPolicy == DropText && (e.t == eventTypeWsTextResultv2 || e.t == eventTypeWsReadyv2) {
+		atomic.AddInt64(&synthesizer.counters.dropped, 1)
+		return
+	}
+	atomic.AddInt64(&synthesizer.counters.overflowed, 1)
+	q.push(e)
+}
@@ -0,0 +1,97 @@
+package tts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventQueueAbsorbsBurst(t *testing.T) {
+	conn := newFakeConn()
+	listener := newBlockingListener()
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithEventQueue(1<<20))
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	<-listener.blocked
+	// far more than the 10 events of the fixed queue, all read while the listener blocks
+	for i := 0; i < 50; i++ {
+		conn.binary(pcm(3200))
+	}
+	waitFor(t, "the burst read", func() bool { return s.Stats().AudioFrames == 51 })
+	if stats := s.Stats(); stats.Overflowed != 0 {
+		t.Errorf("Overflowed = %d, want the burst queued", stats.Overflowed)
+	}
+	close(listener.release)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := listener.count("audio"); n != 51 {
+		t.Errorf("OnAudioResult called %d times, want 51", n)
+	}
+}
+
+func TestEventQueueLimit(t *testing.T) {
+	conn := newFakeConn()
+	listener := newBlockingListener()
+	// room for about 4 frames of 3200 bytes, besides the 10 events of eventChan
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithEventQueue(4*(3200+eventOverhead)))
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	<-listener.blocked
+	for i := 0; i < 20; i++ {
+		conn.binary(pcm(3200))
+	}
+	waitFor(t, "overflow", func() bool { return s.Stats().Overflowed > 0 })
+	time.Sleep(20 * time.Millisecond)
+	// the blocked frame, 10 in eventChan, 1 held by pump, 4 queued and 1 waiting for room
+	if n := s.Stats().AudioFrames; n > 17 {
+		t.Errorf("%d audio frames read while the listener blocks, want reading stalled at the limit", n)
+	}
+	close(listener.release)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := listener.count("audio"); n != 21 {
+		t.Errorf("OnAudioResult called %d times, want all 21 audio frames", n)
+	}
+}
+
+// burstyListener keeps up with the audio but pauses every 25 frames, as a listener
+// flushing a buffer to disk does
+type burstyListener struct {
+	NoopListener
+	frames int
+}
+
+func (l *burstyListener) OnAudioResult(data []byte) {
+	if l.frames++; l.frames%25 == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// BenchmarkEventQueueBurst reports, as stalls/op, how many times reading the frames of a
+// burst had to wait for the bursty listener
+func BenchmarkEventQueueBurst(b *testing.B) {
+	const burst = 100
+	frame := pcm(3200)
+	run := func(b *testing.B, opts ...Option) {
+		var stalls int64
+		for i := 0; i < b.N; i++ {
+			conn := newFakeConn()
+			s := NewSpeechWsv2Synthesizer(0, nil, &burstyListener{}, opts...)
+			startFake(s, conn)
+			for j := 0; j < burst; j++ {
+				conn.binary(frame)
+			}
+			conn.final()
+			if err := s.Wait(); err != nil {
+				b.Fatal(err)
+			}
+			stalls += s.Stats().Overflowed
+		}
+		b.ReportMetric(float64(stalls)/float64(b.N), "stalls/op")
+	}
+	b.Run("fixed", func(b *testing.B) { run(b) })
+	b.Run("queue", func(b *testing.B) { run(b, WithEventQueue(1<<20)) })
+}
@@ -0,0 +1,89 @@
+package tts
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// bufferedFileSink is returned by BufferedFileSink
+type bufferedFileSink struct {
+	mutex sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// BufferedFileSink creates path and returns a writer buffering up to bufSize bytes before
+// writing them to it, saving a syscall per audio frame. The buffer is also written every
+// flushInterval, if positive, so the file keeps up with a slow synthesis, and on Close.
+// The writer has a Flush() error method, called by the synthesizers when used as AudioWriter.
+func BufferedFileSink(path string, bufSize int, flushInterval time.Duration) (io.WriteCloser, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	sink := &bufferedFileSink{file: file, w: bufio.NewWriterSize(file, bufSize)}
+	if flushInterval > 0 {
+		sink.stop = make(chan struct{})
+		sink.done = make(chan struct{})
+		go sink.flushEvery(flushInterval)
+	}
+	return sink, nil
+}
+
+func (s *bufferedFileSink) flushEvery(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *bufferedFileSink) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return 0, os.ErrClosed
+	}
+	return s.w.Write(p)
+}
+
+// Flush writes the buffered bytes to the file
+func (s *bufferedFileSink) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	return s.w.Flush()
+}
+
+// Close flushes the remaining bytes and closes the file
+func (s *bufferedFileSink) Close() error {
+	s.mutex.Lock()
+	file := s.file
+	s.file = nil
+	s.mutex.Unlock()
+	if file == nil {
+		return os.ErrClosed
+	}
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	err := s.w.Flush()
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package tts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBufferedFileSinkWritesAllOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.pcm")
+	sink, err := BufferedFileSink(path, 4096, 0)
+	if err != nil {
+		t.Fatalf("BufferedFileSink() error = %v", err)
+	}
+	var want []byte
+	for i := 0; i < 100; i++ {
+		frame := pcm(97)
+		if _, err := sink.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		want = append(want, frame...)
+	}
+	if info, _ := os.Stat(path); info.Size() >= int64(len(want)) {
+		t.Errorf("%d bytes on disk before Close, want the writes buffered", info.Size())
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%d bytes on disk after Close, want the %d bytes written", len(got), len(want))
+	}
+	if _, err := sink.Write(pcm(1)); err != os.ErrClosed {
+		t.Errorf("Write() after Close error = %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestBufferedFileSinkFlushInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.pcm")
+	sink, err := BufferedFileSink(path, 4096, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("BufferedFileSink() error = %v", err)
+	}
+	defer sink.Close()
+	sink.Write(pcm(100))
+	waitFor(t, "the periodic flush", func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Size() == 100
+	})
+}
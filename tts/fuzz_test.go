@@ -0,0 +1,101 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// FuzzReceiveFrame feeds the frames of a session to receive and eventDispatch through a
+// fakeConn:
+//
+//	go test -run '^$' -fuzz FuzzReceiveFrame ./tts
+//
+// data holds the frames separated by newlines, the first byte of each frame selecting its
+// message type: 't' text, 'b' binary, anything else is taken as the type. The seeds are the
+// frames of testdata/fuzz/corpus. A panic in the session goroutines, which they report as a
+// failure, fails the test, as does a session not ending or delivering more audio than sent.
+func FuzzReceiveFrame(f *testing.F) {
+	seeds, err := filepath.Glob(filepath.Join("testdata", "fuzz", "corpus", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range seeds {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frames := fuzzFrames(data)
+		conn := newFakeConn()
+		conn.frames = make(chan fakeFrame, len(frames)+1)
+		sent := 0
+		for _, frame := range frames {
+			conn.frames <- frame
+			if frame.op == websocket.BinaryMessage {
+				sent += len(frame.data)
+			}
+		}
+		conn.fail(&websocket.CloseError{Code: websocket.CloseNormalClosure})
+
+		listener := &fuzzListener{}
+		s := NewSpeechWsv2Synthesizer(0, nil, listener, WithoutVoiceValidation())
+		s.EnableSubtitle = true
+		startFake(s, conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.WaitContext(ctx); ctx.Err() != nil {
+			t.Fatalf("session of %d frames still running: %v", len(frames), err)
+		}
+		if listener.panic != nil {
+			t.Fatal(listener.panic)
+		}
+		if listener.audio > sent {
+			t.Errorf("OnAudioResult got %d bytes, %d sent", listener.audio, sent)
+		}
+	})
+}
+
+// fuzzFrames splits the input of FuzzReceiveFrame in frames
+func fuzzFrames(data []byte) []fakeFrame {
+	var frames []fakeFrame
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		op := int(line[0])
+		switch line[0] {
+		case 't':
+			op = websocket.TextMessage
+		case 'b':
+			op = websocket.BinaryMessage
+		}
+		frames = append(frames, fakeFrame{op: op, data: line[1:]})
+	}
+	return frames
+}
+
+// fuzzListener keeps the panics reported as failures and counts the audio
+type fuzzListener struct {
+	NoopListener
+	panic error
+	audio int
+}
+
+func (l *fuzzListener) OnAudioResult(data []byte) {
+	l.audio += len(data)
+}
+
+func (l *fuzzListener) OnSynthesisFail(r *SpeechWsv2SynthesisResponse, err error) {
+	if err != nil && strings.HasPrefix(err.Error(), "panic error ocurred!") {
+		l.panic = err
+	}
+}
@@ -0,0 +1,76 @@
+package tts
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Gain is a Transcoder multiplying 16-bit mono pcm by DB decibels, negative values
+// attenuating. Samples pushed beyond full scale are clipped to it, which distorts: keep
+// positive gains small or use NormalizePeak on the complete audio. Clipped counts the
+// samples clipped so far.
+type Gain struct {
+	DB      float64
+	Clipped int
+
+	odd []byte // last byte of an odd length Write
+}
+
+// NewGain creates instance of Gain
+func NewGain(db float64) *Gain {
+	return &Gain{DB: db}
+}
+
+// Write returns pcm with the gain applied
+func (g *Gain) Write(pcm []byte) ([]byte, error) {
+	data := append(g.odd, pcm...)
+	g.odd = nil
+	if len(data)%2 == 1 {
+		g.odd = []byte{data[len(data)-1]}
+		data = data[:len(data)-1]
+	}
+	g.Clipped += scaleSamples(data, math.Pow(10, g.DB/20))
+	return data, nil
+}
+
+// Flush drops a pending half sample
+func (g *Gain) Flush() ([]byte, error) {
+	g.odd = nil
+	return nil, nil
+}
+
+// NormalizePeak returns a copy of 16-bit mono pcm scaled so its loudest sample reaches
+// peakDB decibels relative to full scale, e.g. -1. It needs the complete audio, and never
+// clips as the peak stays below full scale for a negative peakDB. Silence is returned
+// unchanged.
+func NormalizePeak(pcm []byte, peakDB float64) []byte {
+	out := make([]byte, len(pcm)/2*2)
+	copy(out, pcm)
+	peak := 0.0
+	for i := 0; i < len(out); i += 2 {
+		peak = math.Max(peak, math.Abs(float64(int16(binary.LittleEndian.Uint16(out[i:])))))
+	}
+	if peak == 0 {
+		return out
+	}
+	scaleSamples(out, 32767*math.Pow(10, peakDB/20)/peak)
+	return out
+}
+
+// scaleSamples multiplies the 16-bit samples of pcm in place, clipping them to full scale,
+// and returns the number of clipped samples
+func scaleSamples(pcm []byte, factor float64) int {
+	clipped := 0
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := math.Round(float64(int16(binary.LittleEndian.Uint16(pcm[i:]))) * factor)
+		if v > math.MaxInt16 {
+			v = math.MaxInt16
+			clipped++
+		} else if v < math.MinInt16 {
+			v = math.MinInt16
+			clipped++
+		}
+		binary.LittleEndian.PutUint16(pcm[i:], uint16(int16(v)))
+	}
+	return clipped
+}
@@ -0,0 +1,69 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// peak returns the largest absolute sample of 16-bit pcm
+func peak(pcm []byte) float64 {
+	p := 0.0
+	for i := 0; i+1 < len(pcm); i += 2 {
+		p = math.Max(p, math.Abs(float64(int16(binary.LittleEndian.Uint16(pcm[i:])))))
+	}
+	return p
+}
+
+func TestNormalizePeak(t *testing.T) {
+	input := sine(16000, 1600)
+	original := append([]byte(nil), input...)
+	out := NormalizePeak(input, -1)
+	if want := 32767 * math.Pow(10, -1.0/20); math.Abs(peak(out)-want) > 1 {
+		t.Errorf("peak after NormalizePeak(-1) = %g, want %g", peak(out), want)
+	}
+	if !bytes.Equal(input, original) {
+		t.Error("NormalizePeak modified its input")
+	}
+	if silence := NormalizePeak(make([]byte, 320), -1); peak(silence) != 0 || len(silence) != 320 {
+		t.Errorf("NormalizePeak() of silence = %d bytes peaking at %g, want it unchanged", len(silence), peak(silence))
+	}
+}
+
+func TestGain(t *testing.T) {
+	tests := []struct {
+		db          float64
+		wantPeak    float64
+		wantClipped bool
+	}{
+		{-6, sineAmplitude * math.Pow(10, -6.0/20), false},
+		{6, sineAmplitude * math.Pow(10, 6.0/20), false},
+		{20, math.MaxInt16, true},
+	}
+	for _, tt := range tests {
+		input := sine(16000, 1600)
+		original := append([]byte(nil), input...)
+		g := NewGain(tt.db)
+		var out []byte
+		for _, chunk := range [][]byte{input[:1001], input[1001:]} {
+			data, err := g.Write(chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, data...)
+		}
+		if len(out) != len(input) {
+			t.Errorf("%g dB: %d bytes out, want %d", tt.db, len(out), len(input))
+		}
+		if got := peak(out); math.Abs(got-tt.wantPeak) > 1 {
+			t.Errorf("%g dB: peak = %g, want %g", tt.db, got, tt.wantPeak)
+		}
+		if clipped := g.Clipped > 0; clipped != tt.wantClipped {
+			t.Errorf("%g dB: Clipped = %d, want clipping %v", tt.db, g.Clipped, tt.wantClipped)
+		}
+		if !bytes.Equal(input, original) {
+			t.Errorf("%g dB: Write modified its input", tt.db)
+		}
+	}
+}
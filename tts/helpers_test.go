@@ -0,0 +1,364 @@
+package tts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+// errFakeConnClosed is returned by the reads of a closed fakeConn
+var errFakeConnClosed = errors.New("fake connection closed")
+
+// timeoutError is the net.Error of a write exceeding its deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type fakeFrame struct {
+	op   int
+	data []byte
+	err  error
+}
+
+// fakeConn is a wsConn fed by the test: the frames pushed with text, binary and fail are
+// read in order, reads block until one is pushed or the connection is closed. Writes are
+// recorded, and may be made to fail or to stall until their deadline.
+type fakeConn struct {
+	frames    chan fakeFrame
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mutex         sync.Mutex
+	writes        []map[string]interface{}
+	writeDeadline time.Time
+	stallWrites   bool
+	writeErr      error
+	closeErr      error
+	closes        int
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{frames: make(chan fakeFrame, 1024), closed: make(chan struct{})}
+}
+
+func (c *fakeConn) text(format string, args ...interface{}) {
+	c.frames <- fakeFrame{op: websocket.TextMessage, data: []byte(fmt.Sprintf(format, args...))}
+}
+
+func (c *fakeConn) binary(data []byte) {
+	c.frames <- fakeFrame{op: websocket.BinaryMessage, data: data}
+}
+
+// final pushes the final frame
+func (c *fakeConn) final() {
+	c.text(`{"code":0,"message":"success","final":1}`)
+}
+
+// fail makes the next read fail with err, e.g. a *websocket.CloseError
+func (c *fakeConn) fail(err error) {
+	c.frames <- fakeFrame{err: err}
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case <-c.closed:
+		return 0, nil, errFakeConnClosed
+	case f := <-c.frames:
+		return f.op, f.data, f.err
+	}
+}
+
+func (c *fakeConn) WriteJSON(v interface{}) error {
+	c.mutex.Lock()
+	stall, deadline, err := c.stallWrites, c.writeDeadline, c.writeErr
+	c.mutex.Unlock()
+	if stall {
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			expired = time.After(time.Until(deadline))
+		}
+		select {
+		case <-expired:
+			return timeoutError{}
+		case <-c.closed:
+			return errFakeConnClosed
+		}
+	}
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var frame map[string]interface{}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.writes = append(c.writes, frame)
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *fakeConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *fakeConn) SetWriteDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.mutex.Lock()
+	c.closes++
+	err := c.closeErr
+	c.mutex.Unlock()
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
+
+// sent returns the frames written so far, decoded
+func (c *fakeConn) sent() []map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]map[string]interface{}(nil), c.writes...)
+}
+
+// sentActions returns the action of each frame written so far
+func (c *fakeConn) sentActions() []string {
+	var actions []string
+	for _, frame := range c.sent() {
+		actions = append(actions, fmt.Sprint(frame["action"]))
+	}
+	return actions
+}
+
+// startFake starts a session of s on conn as Prepare does once connected
+func startFake(s *SpeechWsv2Synthesizer, conn wsConn) {
+	if s.SessionId == "" {
+		s.SessionId = "test-session"
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.start(conn, &SpeechWsv2SynthesisResponse{SessionId: s.SessionId, RequestId: "test-request"})
+}
+
+// recordListener records the callbacks of a session
+type recordListener struct {
+	mutex  sync.Mutex
+	events []string
+	audio  []byte
+	texts  []*SpeechWsv2SynthesisResponse
+	errs   []error
+}
+
+func (l *recordListener) record(format string, args ...interface{}) {
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+}
+
+func (l *recordListener) OnSynthesisStart(r *SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record("start")
+}
+
+func (l *recordListener) OnSynthesisEnd(r *SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record("end")
+}
+
+func (l *recordListener) OnAudioResult(data []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.audio = append(l.audio, data...)
+	l.record("audio %d", len(data))
+}
+
+func (l *recordListener) OnTextResult(r *SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.texts = append(l.texts, r)
+	l.record("text %d", len(r.Result.Subtitles))
+}
+
+func (l *recordListener) OnSynthesisFail(r *SpeechWsv2SynthesisResponse, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.errs = append(l.errs, err)
+	l.record("fail")
+}
+
+// count returns the number of events starting with kind
+func (l *recordListener) count(kind string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	n := 0
+	for _, e := range l.events {
+		if e == kind || strings.HasPrefix(e, kind+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func (l *recordListener) eventList() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func (l *recordListener) failures() []error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]error(nil), l.errs...)
+}
+
+func (l *recordListener) audioBytes() []byte {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]byte(nil), l.audio...)
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// checkGoroutines fails the test when more goroutines run than before, once the ones
+// exiting had the time to
+func checkGoroutines(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines running, %d before:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// pcm returns n bytes of 16-bit pcm counting up, so that slices of it can be told apart
+func pcm(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+// mockService is a local server standing in for the service, see mockServer
+type mockService struct {
+	*httptest.Server
+	mutex sync.Mutex
+	reqs  []*http.Request
+}
+
+// requests returns the upgrade requests received so far
+func (m *mockService) requests() []*http.Request {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*http.Request(nil), m.reqs...)
+}
+
+// mockServer serves handler to the sessions dialed until the test ends, in place of the
+// service. The handshake helpers send what the service does before audio.
+func mockServer(t *testing.T, handler func(conn *websocket.Conn)) *mockService {
+	t.Helper()
+	m := &mockService{}
+	upgrader := websocket.Upgrader{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		m.reqs = append(m.reqs, r)
+		m.mutex.Unlock()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+	protocol, host := wsProtocolv2, wsHostv2
+	wsProtocolv2, wsHostv2 = "ws", strings.TrimPrefix(m.URL, "http://")
+	t.Cleanup(func() {
+		wsProtocolv2, wsHostv2 = protocol, host
+		m.CloseClientConnections()
+		m.Close()
+	})
+	return m
+}
+
+// handshake sends the handshake response and the ready frame
+func handshake(conn *websocket.Conn) {
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","request_id":"test-request"}`))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","ready":1}`))
+}
+
+// drain reads until the client closes the connection
+func drain(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// synthesize serves a session as the service does: handshake, then once the client sent
+// ACTION_COMPLETE the audio frames given and the final frame
+func synthesize(conn *websocket.Conn, audio ...[]byte) {
+	handshake(conn)
+	for {
+		var frame map[string]interface{}
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		if frame["action"] == "ACTION_COMPLETE" {
+			break
+		}
+	}
+	for _, data := range audio {
+		conn.WriteMessage(websocket.BinaryMessage, data)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+	drain(conn)
+}
+
+// testCredential is accepted by the mock server, which checks no signature
+var testCredential = common.NewCredential("AKIDexample", "secret")
+
+// refusedHost returns the address of a port nothing listens on anymore
+func refusedHost(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := l.Addr().String()
+	l.Close()
+	return host
+}
@@ -0,0 +1,86 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+)
+
+// MP3TagWriter writes an ID3v2.3 tag carrying Title, Artist and Comment ahead of the mp3
+// audio written to W, so players show them. Use it as AudioWriter with Codec mp3, e.g.
+// with the text as title and the voice name as artist; empty fields are left out. The tag
+// is written on the first Write, changing the fields afterwards has no effect. Flush is
+// passed on to W.
+type MP3TagWriter struct {
+	W       io.Writer
+	Title   string
+	Artist  string
+	Comment string
+
+	tagged bool
+}
+
+// NewMP3TagWriter creates instance of MP3TagWriter
+func NewMP3TagWriter(w io.Writer, title, artist, comment string) *MP3TagWriter {
+	return &MP3TagWriter{W: w, Title: title, Artist: artist, Comment: comment}
+}
+
+// Write writes p, preceded by the tag on the first call
+func (w *MP3TagWriter) Write(p []byte) (int, error) {
+	if !w.tagged {
+		if _, err := w.W.Write(ID3v2Tag(w.Title, w.Artist, w.Comment)); err != nil {
+			return 0, err
+		}
+		w.tagged = true
+	}
+	return w.W.Write(p)
+}
+
+// Flush flushes W if it has a Flush() error method
+func (w *MP3TagWriter) Flush() error {
+	if f, ok := w.W.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// ID3v2Tag returns an ID3v2.3 tag with the TIT2 (title), TPE1 (artist) and COMM (comment)
+// frames of the non empty arguments, to prepend to mp3 audio. Texts are UTF-16 encoded,
+// which all ID3v2.3 readers support.
+func ID3v2Tag(title, artist, comment string) []byte {
+	var frames bytes.Buffer
+	if title != "" {
+		writeID3Frame(&frames, "TIT2", append([]byte{1}, id3Text(title)...))
+	}
+	if artist != "" {
+		writeID3Frame(&frames, "TPE1", append([]byte{1}, id3Text(artist)...))
+	}
+	if comment != "" {
+		// encoding, language, empty description, text
+		body := append([]byte{1}, "und"...)
+		body = append(body, id3Text("")...)
+		writeID3Frame(&frames, "COMM", append(body, id3Text(comment)...))
+	}
+	size := frames.Len()
+	tag := []byte{'I', 'D', '3', 3, 0, 0,
+		// synchsafe size, 7 bits per byte
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(tag, frames.Bytes()...)
+}
+
+func writeID3Frame(buf *bytes.Buffer, id string, body []byte) {
+	buf.WriteString(id)
+	binary.Write(buf, binary.BigEndian, uint32(len(body)))
+	buf.Write([]byte{0, 0}) // flags
+	buf.Write(body)
+}
+
+// id3Text encodes s as UTF-16LE with a byte order mark and a terminator
+func id3Text(s string) []byte {
+	text := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		text = append(text, byte(u), byte(u>>8))
+	}
+	return append(text, 0, 0)
+}
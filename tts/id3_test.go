@@ -0,0 +1,88 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// id3Frames parses the frames of an ID3v2.3 tag, failing on an invalid header
+func id3Frames(t *testing.T, data []byte) (map[string][]byte, []string, int) {
+	t.Helper()
+	if len(data) < 10 || string(data[:3]) != "ID3" || data[3] != 3 || data[4] != 0 || data[5] != 0 {
+		t.Fatalf("data starts with % x, want an ID3v2.3 header", data[:10])
+	}
+	size := 0
+	for _, b := range data[6:10] {
+		if b&0x80 != 0 {
+			t.Fatalf("size % x is not synchsafe", data[6:10])
+		}
+		size = size<<7 | int(b)
+	}
+	end := 10 + size
+	if end > len(data) {
+		t.Fatalf("tag size %d exceeds the %d bytes written", size, len(data))
+	}
+	frames := map[string][]byte{}
+	var ids []string
+	for p := 10; p < end; {
+		id := string(data[p : p+4])
+		n := int(binary.BigEndian.Uint32(data[p+4:]))
+		frames[id] = data[p+10 : p+10+n]
+		ids = append(ids, id)
+		p += 10 + n
+	}
+	return frames, ids, end
+}
+
+// id3String decodes a UTF-16 text frame body as written by ID3v2Tag
+func id3String(body []byte) string {
+	var units []uint16
+	for i := 3; i+1 < len(body); i += 2 { // encoding byte, byte order mark
+		if body[i] == 0 && body[i+1] == 0 {
+			break
+		}
+		units = append(units, uint16(body[i])|uint16(body[i+1])<<8)
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestMP3TagWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewMP3TagWriter(&out, "你好，世界", "智瑜", "tts")
+	audio := []byte{0xff, 0xfb, 0x90, 0x64}
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(audio); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	frames, ids, end := id3Frames(t, out.Bytes())
+	if want := []string{"TIT2", "TPE1", "COMM"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("frames %v, want %v", ids, want)
+	}
+	if title := id3String(frames["TIT2"]); title != "你好，世界" {
+		t.Errorf("TIT2 = %q", title)
+	}
+	if artist := id3String(frames["TPE1"]); artist != "智瑜" {
+		t.Errorf("TPE1 = %q", artist)
+	}
+	if comm := frames["COMM"]; string(comm[1:4]) != "und" {
+		t.Errorf("COMM language = %q, want und", comm[1:4])
+	}
+	if rest := out.Bytes()[end:]; !bytes.Equal(rest, append(audio, audio...)) {
+		t.Errorf("audio after the tag = % x, want it once per Write", rest)
+	}
+}
+
+func TestID3v2TagOmitsEmptyFields(t *testing.T) {
+	_, ids, _ := id3Frames(t, ID3v2Tag("", "智瑜", ""))
+	if len(ids) != 1 || ids[0] != "TPE1" {
+		t.Errorf("frames %v, want only TPE1", ids)
+	}
+	if tag := ID3v2Tag("", "", ""); len(tag) != 10 {
+		t.Errorf("empty tag is %d bytes, want the bare 10 bytes header", len(tag))
+	}
+}
@@ -0,0 +1,102 @@
+//go:build integration
+// +build integration
+
+package tts_test
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+	"github.com/showntop/tencentcloud-speech-sdk-go/tts"
+)
+
+// The integration test runs a real session against the service to catch protocol drift.
+// It needs
+//
+//	TENCENTCLOUD_APPID       the account APPID
+//	TENCENTCLOUD_SECRET_ID   the SecretId
+//	TENCENTCLOUD_SECRET_KEY  the SecretKey
+//	TENCENTCLOUD_TOKEN       optional, for temporary credentials
+//
+// and is skipped when any of the required ones is missing:
+//
+//	go test -tags integration -run Integration ./tts
+const envAppID = "TENCENTCLOUD_APPID"
+
+type integrationListener struct {
+	tts.NoopListener
+	mutex     sync.Mutex
+	audio     int
+	subtitles int
+	err       error
+}
+
+func (l *integrationListener) OnAudioResult(data []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.audio += len(data)
+}
+
+func (l *integrationListener) OnTextResult(r *tts.SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.subtitles += len(r.Result.Subtitles)
+}
+
+func (l *integrationListener) OnSynthesisEnd(r *tts.SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.subtitles += len(r.Result.Subtitles)
+}
+
+func (l *integrationListener) OnSynthesisFail(r *tts.SpeechWsv2SynthesisResponse, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.err = err
+}
+
+func TestIntegrationSynthesis(t *testing.T) {
+	appID, err := strconv.ParseInt(os.Getenv(envAppID), 10, 64)
+	if err != nil {
+		t.Skipf("%s is not set", envAppID)
+	}
+	credential, err := common.NewCredentialFromEnv()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	l := &integrationListener{}
+	synthesizer := tts.NewSpeechWsv2Synthesizer(appID, credential, l)
+	synthesizer.EnableSubtitle = true
+	synthesizer.PrepareTimeout = 10 * time.Second
+	synthesizer.DrainTimeout = 30 * time.Second
+	if err := synthesizer.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer synthesizer.Abort()
+	if err := synthesizer.Send("腾讯云语音合成集成测试。"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := synthesizer.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if err := synthesizer.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.err != nil {
+		t.Fatalf("session %s failed: %v", synthesizer.Session().SessionId, l.err)
+	}
+	if l.audio == 0 {
+		t.Error("no audio received")
+	}
+	if l.subtitles == 0 {
+		t.Error("no subtitles received")
+	}
+}
@@ -0,0 +1,72 @@
+package tts
+
+import "strings"
+
+// interleaver holds back, with WithInterleavedOrder, the audio frames no subtitle covered
+// yet: a frame is released once a text frame reported a subtitle ending after the frame
+// starts playing, so OnTextResult always precedes the audio it describes. The start of a
+// frame is its playout offset, computed from the pcm bytes received before it.
+type interleaver struct {
+	synthesizer    *SpeechWsv2Synthesizer
+	bytesPerSecond int64
+	coveredMs      int64 // the greatest EndTime reported so far
+	held           []heldAudio
+}
+
+type heldAudio struct {
+	startMs int64
+	e       speechWsSynthesisEventv2
+}
+
+// newInterleaver returns nil unless WithInterleavedOrder is set and the subtitles can be
+// matched to the audio, that is with EnableSubtitle and pcm
+func (synthesizer *SpeechWsv2Synthesizer) newInterleaver() *interleaver {
+	if !synthesizer.interleaved || !synthesizer.EnableSubtitle ||
+		strings.ToLower(synthesizer.Codec) != "pcm" || synthesizer.SampleRate <= 0 {
+		return nil
+	}
+	return &interleaver{synthesizer: synthesizer, bytesPerSecond: synthesizer.SampleRate * 2}
+}
+
+// hold holds the audio event e, whose first byte is the offset-th of the session, until a
+// subtitle covers it and reports whether it did; e is to be emitted at once otherwise
+func (o *interleaver) hold(e speechWsSynthesisEventv2, offset int64) bool {
+	if o == nil {
+		return false
+	}
+	startMs := offset * 1000 / o.bytesPerSecond
+	if len(o.held) == 0 && startMs < o.coveredMs {
+		return false
+	}
+	o.held = append(o.held, heldAudio{startMs: startMs, e: e})
+	return true
+}
+
+// text records the subtitles of a text frame already emitted and releases the audio they
+// cover
+func (o *interleaver) text(subs []Synthesisv2Subtitle) {
+	if o == nil {
+		return
+	}
+	for _, sub := range subs {
+		if sub.EndTime > o.coveredMs {
+			o.coveredMs = sub.EndTime
+		}
+	}
+	i := 0
+	for ; i < len(o.held) && o.held[i].startMs < o.coveredMs; i++ {
+		o.synthesizer.emit(o.held[i].e)
+	}
+	o.held = o.held[i:]
+}
+
+// flush releases all the audio held, before the end or the failure of the session
+func (o *interleaver) flush() {
+	if o == nil {
+		return
+	}
+	for _, h := range o.held {
+		o.synthesizer.emit(h.e)
+	}
+	o.held = nil
+}
@@ -0,0 +1,69 @@
+package tts
+
+import (
+	"reflect"
+	"testing"
+)
+
+// subtitleFrame pushes a text frame of one subtitle playing from begin to end ms
+func subtitleFrame(conn *fakeConn, text string, begin, end int) {
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":%q,"BeginTime":%d,"EndTime":%d}]}}`, text, begin, end)
+}
+
+func TestInterleavedOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+	}{
+		// 3200 bytes are 100ms of 16kHz pcm: the frames start at 0, 100, 200 and 300ms
+		{"interleaved", []Option{WithInterleavedOrder()},
+			[]string{"start", "text 1", "audio 3200", "audio 3200", "text 1", "audio 3200", "audio 3200", "end"}},
+		{"as received", nil,
+			[]string{"start", "audio 3200", "audio 3200", "text 1", "audio 3200", "text 1", "audio 3200", "end"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeConn()
+			listener := &recordListener{}
+			s := NewSpeechWsv2Synthesizer(0, nil, listener, tt.options...)
+			s.Codec = "pcm"
+			s.SampleRate = 16000
+			s.EnableSubtitle = true
+			startFake(s, conn)
+			conn.binary(pcm(3200))
+			conn.binary(pcm(3200))
+			subtitleFrame(conn, "你", 0, 150)
+			conn.binary(pcm(3200))
+			subtitleFrame(conn, "好", 150, 300)
+			// not covered by any subtitle, released before the end
+			conn.binary(pcm(3200))
+			conn.final()
+			if err := s.Wait(); err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+			if got := listener.eventList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterleavedOrderNeedsSubtitles(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithInterleavedOrder())
+	s.Codec = "pcm"
+	s.SampleRate = 16000
+	startFake(s, conn)
+	conn.binary(pcm(3200))
+	subtitleFrame(conn, "你", 0, 150)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []string{"start", "audio 3200", "text 1", "end"}
+	if got := listener.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package tts
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestFailedPrepareLeavesNoGoroutine checks that no receive or eventDispatch goroutine
+// survives a failed Prepare, whichever step fails
+func TestFailedPrepareLeavesNoGoroutine(t *testing.T) {
+	reject := func(code int) func(conn *websocket.Conn) {
+		return func(conn *websocket.Conn) {
+			conn.WriteJSON(map[string]interface{}{"code": code, "message": "rejected"})
+			drain(conn)
+		}
+	}
+	prepare := func(s *SpeechWsv2Synthesizer) error { return s.Prepare() }
+	tests := []struct {
+		name    string
+		handler func(conn *websocket.Conn)
+		refused bool
+		options []Option
+		run     func(s *SpeechWsv2Synthesizer) error
+	}{
+		{name: "refused", refused: true, run: prepare},
+		{name: "auth failed", handler: reject(4002), run: prepare},
+		{name: "unsupported protocol", handler: func(conn *websocket.Conn) {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","version":"v3"}`))
+			drain(conn)
+		}, run: prepare},
+		{name: "ready timeout", handler: func(conn *websocket.Conn) {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success"}`))
+			drain(conn)
+		}, run: prepare},
+		{name: "frame capture", handler: func(conn *websocket.Conn) {
+			handshake(conn)
+			drain(conn)
+		}, options: []Option{WithFrameCapture(filepath.Join(t.TempDir(), "missing", "frames"))}, run: prepare},
+		{name: "verify credential", handler: reject(4002), run: func(s *SpeechWsv2Synthesizer) error {
+			return s.VerifyCredential(context.Background())
+		}},
+		{name: "retries exhausted", handler: reject(5000),
+			options: []Option{WithBackoff(ConstantBackoff{Delay: time.Millisecond})},
+			run: func(s *SpeechWsv2Synthesizer) error {
+				return s.PrepareWithRetry(context.Background(), 3)
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer(t, func(conn *websocket.Conn) {
+				if tt.handler != nil {
+					tt.handler(conn)
+				}
+			})
+			if tt.refused {
+				wsHostv2 = refusedHost(t)
+			}
+			s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, tt.options...)
+			s.ConnectTimeout = time.Second
+			s.PrepareTimeout = 100 * time.Millisecond
+			before := runtime.NumGoroutine()
+			if err := tt.run(s); err == nil {
+				s.Abort()
+				t.Fatal("error = nil, want the failure")
+			}
+			checkGoroutines(t, before)
+		})
+	}
+}
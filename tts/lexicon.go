@@ -0,0 +1,45 @@
+package tts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// lexiconParam is the request parameter carrying the WithLexicon dictionary, a name of
+// this SDK and not of the service documentation
+const lexiconParam = "Lexicon"
+
+var pinyinSyllable = regexp.MustCompile(`^[a-zv]+[1-5]$`)
+
+// validateLexicon checks that every pronunciation is either pinyin syllables with tone
+// numbers separated by spaces ("zhong4 chong2", ü written v) or IPA between slashes
+// ("/təˈmɑːtəʊ/")
+func validateLexicon(entries map[string]string) error {
+	for word, pronunciation := range entries {
+		if strings.TrimSpace(word) == "" {
+			return fmt.Errorf("lexicon: empty word")
+		}
+		if len(pronunciation) > 2 && strings.HasPrefix(pronunciation, "/") && strings.HasSuffix(pronunciation, "/") {
+			continue
+		}
+		syllables := strings.Fields(pronunciation)
+		if len(syllables) == 0 {
+			return fmt.Errorf("lexicon: empty pronunciation for %q", word)
+		}
+		for _, syllable := range syllables {
+			if !pinyinSyllable.MatchString(syllable) {
+				return fmt.Errorf("lexicon: %q for %q is neither pinyin with tone numbers nor /IPA/", pronunciation, word)
+			}
+		}
+	}
+	return nil
+}
+
+// encodeLexicon returns the dictionary as unpadded base64url of its JSON object, keys sorted
+func encodeLexicon(entries map[string]string) string {
+	data, _ := json.Marshal(entries)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestLexiconInSignedURL(t *testing.T) {
+	entries := map[string]string{"重庆": "chong2 qing4", "tomato": "/təˈmɑːtəʊ/"}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithLexicon(entries))
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	u, _ := url.Parse(signed)
+	encoded := u.Query().Get(lexiconParam)
+	if encoded != encodeLexicon(entries) {
+		t.Fatalf("%s = %q, want %q", lexiconParam, encoded, encodeLexicon(entries))
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("%s is not base64url: %v", lexiconParam, err)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 2 || decoded["重庆"] != "chong2 qing4" {
+		t.Errorf("%s decodes to %v, %v, want the entries", lexiconParam, decoded, err)
+	}
+	// the Lexicon parameter is signed along with the others
+	if got, want := u.Query().Get("Signature"), s.genWsSignature(s.buildURL(false), testCredential.SecretKey); got != want {
+		t.Errorf("Signature = %q, want %q", got, want)
+	}
+}
+
+func TestLexiconOverriddenByExtParam(t *testing.T) {
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithLexicon(map[string]string{"重庆": "chong2 qing4"}))
+	s.ExtParam = map[string]string{lexiconParam: "server-format"}
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	u, _ := url.Parse(signed)
+	if got := u.Query().Get(lexiconParam); got != "server-format" {
+		t.Errorf("%s = %q, want the ExtParam entry", lexiconParam, got)
+	}
+}
+
+func TestValidateLexicon(t *testing.T) {
+	tests := []struct {
+		entries map[string]string
+		wantErr bool
+	}{
+		{map[string]string{"绿": "lv4"}, false},
+		{map[string]string{"重庆": "chong2 qing4"}, false},
+		{map[string]string{"tomato": "/təˈmɑːtəʊ/"}, false},
+		{map[string]string{"重庆": "chongqing"}, true},
+		{map[string]string{"重庆": ""}, true},
+		{map[string]string{" ": "a1"}, true},
+		{map[string]string{"x": "//"}, true},
+	}
+	for _, tt := range tests {
+		if err := validateLexicon(tt.entries); (err != nil) != tt.wantErr {
+			t.Errorf("validateLexicon(%v) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+		}
+	}
+}
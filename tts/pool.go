@@ -0,0 +1,122 @@
+package tts
+
+import (
+	"sync"
+
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+// SynthesizerPool hands out reusable SpeechWsv2Synthesizer built from the same options.
+// Put resets a synthesizer fully: its session state is cleared and its configuration,
+// exported fields and options alike, is restored to the one of the pool, so the fields a
+// caller changed after Get don't carry over to the next one.
+//
+// The options run once for the pool. Reuse only saves allocating the synthesizer: the
+// channels and buffers of a session are allocated for each one, so the saving is small.
+type SynthesizerPool struct {
+	pool     sync.Pool
+	template *SpeechWsv2Synthesizer // configuration restored by Put, never started
+}
+
+// NewSynthesizerPool creates instance of SynthesizerPool
+func NewSynthesizerPool(appID int64, credential *common.Credential, opts ...Option) *SynthesizerPool {
+	p := &SynthesizerPool{template: NewSpeechWsv2Synthesizer(appID, credential, nil, opts...)}
+	p.pool.New = func() interface{} {
+		synthesizer := NewSpeechWsv2Synthesizer(appID, credential, nil)
+		synthesizer.restoreConfig(p.template)
+		return synthesizer
+	}
+	return p
+}
+
+// Get returns a synthesizer ready for Prepare, reporting to listener
+func (p *SynthesizerPool) Get(listener SpeechWsv2SynthesisListener) *SpeechWsv2Synthesizer {
+	synthesizer := p.pool.Get().(*SpeechWsv2Synthesizer)
+	synthesizer.listener = listener
+	return synthesizer
+}
+
+// Put gives back a synthesizer whose session has ended. A still running one is aborted
+// and dropped, so that no session is left alive.
+func (p *SynthesizerPool) Put(synthesizer *SpeechWsv2Synthesizer) {
+	if err := synthesizer.Reset(); err != nil {
+		synthesizer.Abort()
+		return
+	}
+	synthesizer.restoreConfig(p.template)
+	synthesizer.listener = nil
+	p.pool.Put(synthesizer)
+}
+
+// restoreConfig copies the configuration of from, the fields set by the constructor and
+// the options, ExtParam being copied too
+func (synthesizer *SpeechWsv2Synthesizer) restoreConfig(from *SpeechWsv2Synthesizer) {
+	synthesizer.Credential = from.Credential
+	synthesizer.CredentialProvider = from.CredentialProvider
+	synthesizer.action = from.action
+	synthesizer.AppID = from.AppID
+	synthesizer.Timestamp = from.Timestamp
+	synthesizer.Expired = from.Expired
+	synthesizer.SessionId = from.SessionId
+	synthesizer.Text = from.Text
+	synthesizer.ModelType = from.ModelType
+	synthesizer.VoiceType = from.VoiceType
+	synthesizer.SampleRate = from.SampleRate
+	synthesizer.Codec = from.Codec
+	synthesizer.Speed = from.Speed
+	synthesizer.Volume = from.Volume
+	synthesizer.EnableSubtitle = from.EnableSubtitle
+	synthesizer.EmotionCategory = from.EmotionCategory
+	synthesizer.EmotionIntensity = from.EmotionIntensity
+	synthesizer.SegmentRate = from.SegmentRate
+	synthesizer.ExtParam = nil
+	if from.ExtParam != nil {
+		synthesizer.ExtParam = make(map[string]string, len(from.ExtParam))
+		for k, v := range from.ExtParam {
+			synthesizer.ExtParam[k] = v
+		}
+	}
+	synthesizer.SignatureAlgorithm = from.SignatureAlgorithm
+	synthesizer.TextMode = from.TextMode
+	synthesizer.MaxChunkChars = from.MaxChunkChars
+	synthesizer.AudioWriter = from.AudioWriter
+	synthesizer.Transcoder = from.Transcoder
+	synthesizer.ProxyURL = from.ProxyURL
+	synthesizer.ConnectTimeout = from.ConnectTimeout
+	synthesizer.PrepareTimeout = from.PrepareTimeout
+	synthesizer.DrainTimeout = from.DrainTimeout
+	synthesizer.IdleTimeout = from.IdleTimeout
+	synthesizer.WriteTimeout = from.WriteTimeout
+	synthesizer.MaxSessionDuration = from.MaxSessionDuration
+	synthesizer.MaxAudioBytes = from.MaxAudioBytes
+	synthesizer.Debug = from.Debug
+	synthesizer.DebugFunc = from.DebugFunc
+
+	synthesizer.skipVoiceValidation = from.skipVoiceValidation
+	synthesizer.frameCapturePath = from.frameCapturePath
+	synthesizer.adaptiveTimeout = from.adaptiveTimeout
+	synthesizer.presigned = from.presigned
+	synthesizer.userAgent = from.userAgent
+	synthesizer.autoSplit = from.autoSplit
+	synthesizer.strict = from.strict
+	synthesizer.dropPolicy = from.dropPolicy
+	synthesizer.fallbackHosts = from.fallbackHosts
+	synthesizer.skipRateValidation = from.skipRateValidation
+	synthesizer.flushOnPunctuation = from.flushOnPunctuation
+	synthesizer.lexicon = from.lexicon
+	synthesizer.queryMutator = from.queryMutator
+	synthesizer.jsonDebug = from.jsonDebug
+	synthesizer.noSubtitleMerge = from.noSubtitleMerge
+	synthesizer.collectAudio = from.collectAudio
+	synthesizer.done = from.done
+	synthesizer.requireFinal = from.requireFinal
+	synthesizer.breaker = from.breaker
+	synthesizer.signatureRetry = from.signatureRetry
+	synthesizer.queueLimit = from.queueLimit
+	synthesizer.recorder = from.recorder
+	synthesizer.tracer = from.tracer
+	synthesizer.backoff = from.backoff
+	synthesizer.interleaved = from.interleaved
+	synthesizer.netDialer = from.netDialer
+	synthesizer.binaryHeaders = from.binaryHeaders
+}
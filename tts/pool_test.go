@@ -0,0 +1,119 @@
+package tts
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSynthesizerPoolResetsReturned(t *testing.T) {
+	pool := NewSynthesizerPool(0, testCredential)
+	listener := &recordListener{}
+	s := pool.Get(listener)
+	conn := newFakeConn()
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	pool.Put(s)
+	if s.started || s.SessionId != "" || s.conn != nil || s.listener != nil {
+		t.Errorf("synthesizer put back with started %v, SessionId %q, conn %v, listener %v, want it reset",
+			s.started, s.SessionId, s.conn, s.listener)
+	}
+}
+
+func TestSynthesizerPoolAbortsRunning(t *testing.T) {
+	pool := NewSynthesizerPool(0, testCredential)
+	listener := &recordListener{}
+	s := pool.Get(listener)
+	conn := newFakeConn()
+	startFake(s, conn)
+	pool.Put(s)
+	// the session is aborted: Wait returns without a final frame, the connection is closed
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() = %v after Put, want nil", err)
+	}
+	conn.mutex.Lock()
+	closes := conn.closes
+	conn.mutex.Unlock()
+	if closes == 0 {
+		t.Error("Put left the connection of a running synthesizer open")
+	}
+	if s.listener != listener {
+		t.Error("Put reset a running synthesizer instead of dropping it")
+	}
+}
+
+func TestSynthesizerPoolRestoresConfig(t *testing.T) {
+	ext := map[string]string{"key": "value"}
+	withExt := func(s *SpeechWsv2Synthesizer) { s.ExtParam = ext }
+	pool := NewSynthesizerPool(1300000000, testCredential, WithModelType(ModelTypeDefault), withExt)
+	want := pool.template
+	s := pool.Get(&recordListener{})
+
+	// change every exported field of a basic kind, as a caller may after Get
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Int, reflect.Int64:
+			field.SetInt(field.Int() + 7)
+		case reflect.Float64:
+			field.SetFloat(field.Float() + 0.5)
+		case reflect.String:
+			field.SetString(field.String() + "changed")
+		case reflect.Bool:
+			field.SetBool(!field.Bool())
+		case reflect.Ptr:
+			field.Set(reflect.New(field.Type().Elem()))
+		case reflect.Func:
+			field.Set(reflect.MakeFunc(field.Type(), func([]reflect.Value) []reflect.Value { return nil }))
+		}
+	}
+	s.ExtParam["key"] = "changed"
+	s.ExtParam["other"] = "added"
+	s.AudioWriter = &bytes.Buffer{}
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	WithoutVoiceValidation()(s)
+	WithCollect()(s)
+
+	pool.Put(s)
+	got, wantV := reflect.ValueOf(s).Elem(), reflect.ValueOf(want).Elem()
+	for i := 0; i < got.NumField(); i++ {
+		if !got.Field(i).CanSet() {
+			continue
+		}
+		name := got.Type().Field(i).Name
+		if !reflect.DeepEqual(got.Field(i).Interface(), wantV.Field(i).Interface()) {
+			t.Errorf("%s = %v after Put, want %v", name, got.Field(i).Interface(), wantV.Field(i).Interface())
+		}
+	}
+	if s.skipVoiceValidation || s.collectAudio {
+		t.Error("options applied after Get kept after Put")
+	}
+	if ext["key"] != "value" || len(ext) != 1 {
+		t.Errorf("ExtParam of the options changed to %v", ext)
+	}
+}
+
+func BenchmarkSynthesizerPool(b *testing.B) {
+	listener := &recordListener{}
+	b.Run("pooled", func(b *testing.B) {
+		pool := NewSynthesizerPool(0, testCredential)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pool.Put(pool.Get(listener))
+		}
+	})
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewSpeechWsv2Synthesizer(0, testCredential, listener)
+		}
+	})
+}
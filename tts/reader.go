@@ -0,0 +1,75 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// readerChunkSize is the read size of SynthesizeReader
+const readerChunkSize = 4096
+
+// SynthesizeReader sends the text read from r as it arrives, e.g. streamed from a language
+// model, and calls Complete at EOF. Call it after Prepare, then Wait. A multi-byte rune split
+// across reads is held back until complete, so every chunk sent is valid UTF-8, and a read
+// holding more than MaxChunkChars runes is sent in several chunks.
+//
+// ctx is checked between reads, a Read blocked in r is not interrupted. On error Complete is
+// not called, Close or Abort the session.
+func (synthesizer *SpeechWsv2Synthesizer) SynthesizeReader(ctx context.Context, r io.Reader) error {
+	buf := make([]byte, readerChunkSize)
+	pending := 0 // bytes of an incomplete rune kept at the start of buf
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := r.Read(buf[pending:])
+		n += pending
+		end := completeRunes(buf[:n])
+		if end > 0 {
+			if serr := synthesizer.sendRead(string(buf[:end])); serr != nil {
+				return serr
+			}
+		}
+		pending = copy(buf, buf[end:n])
+		if err == io.EOF {
+			if pending > 0 {
+				return fmt.Errorf("text ends with an incomplete UTF-8 sequence %q", buf[:pending])
+			}
+			return synthesizer.Complete()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendRead sends text read by SynthesizeReader in chunks of at most MaxChunkChars runes
+func (synthesizer *SpeechWsv2Synthesizer) sendRead(text string) error {
+	max := synthesizer.MaxChunkChars
+	if max <= 0 {
+		return synthesizer.Send(text)
+	}
+	for _, part := range splitRunes(text, max) {
+		if err := synthesizer.Send(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeRunes returns the length of p without a trailing incomplete rune. Invalid bytes
+// that can't start a longer rune are kept, Send gets them as they are.
+func completeRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return i
+		}
+		break
+	}
+	return len(p)
+}
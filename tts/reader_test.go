@@ -0,0 +1,109 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// chunkedReader returns the text in reads of the given sizes, cutting runes apart
+type chunkedReader struct {
+	data  []byte
+	sizes []int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := len(r.data)
+	if len(r.sizes) > 0 {
+		n, r.sizes = r.sizes[0], r.sizes[1:]
+	}
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+	n = copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// sentTexts returns the text of the ACTION_SYNTHESIS frames written so far, in order
+func sentTexts(conn *fakeConn) []string {
+	var texts []string
+	for _, frame := range conn.sent() {
+		if frame["action"] == "ACTION_SYNTHESIS" {
+			texts = append(texts, frame["data"].(string))
+		}
+	}
+	return texts
+}
+
+func TestSynthesizeReaderAwkwardChunks(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	// "你好" is 6 bytes: the reads cut 你 after 1 byte and 好 after 2
+	r := &chunkedReader{data: []byte("你好, world!"), sizes: []int{1, 3, 1, 1, 4, 10}}
+	if err := s.SynthesizeReader(context.Background(), r); err != nil {
+		t.Fatalf("SynthesizeReader() error = %v", err)
+	}
+	got := sentTexts(conn)
+	want := []string{"你", "好", ", wo", "rld!"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("texts sent %q, want %q", got, want)
+	}
+	if actions := conn.sentActions(); actions[len(actions)-1] != "ACTION_COMPLETE" {
+		t.Errorf("frames written = %v, want ACTION_COMPLETE last", actions)
+	}
+}
+
+func TestSynthesizeReaderLargeReader(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	text := strings.Repeat("a", 3000)
+	if err := s.SynthesizeReader(context.Background(), strings.NewReader(text)); err != nil {
+		t.Fatalf("SynthesizeReader() error = %v", err)
+	}
+	got := sentTexts(conn)
+	for _, chunk := range got {
+		if n := utf8.RuneCountInString(chunk); n > s.MaxChunkChars {
+			t.Errorf("chunk of %d runes sent, want at most MaxChunkChars %d", n, s.MaxChunkChars)
+		}
+	}
+	if strings.Join(got, "") != text {
+		t.Errorf("texts sent add up to %d bytes, want the %d bytes read", len(strings.Join(got, "")), len(text))
+	}
+}
+
+func TestSynthesizeReaderIncompleteRuneAtEOF(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	err := s.SynthesizeReader(context.Background(), strings.NewReader("ab\xe4\xbd"))
+	if err == nil || !strings.Contains(err.Error(), "incomplete UTF-8") {
+		t.Errorf("SynthesizeReader() error = %v, want the incomplete rune reported", err)
+	}
+	if actions := conn.sentActions(); len(actions) != 1 || actions[0] != "ACTION_SYNTHESIS" {
+		t.Errorf("frames written = %v, want the complete text and no ACTION_COMPLETE", actions)
+	}
+}
+
+func TestSynthesizeReaderCanceled(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.SynthesizeReader(ctx, strings.NewReader("你好")); !errors.Is(err, context.Canceled) {
+		t.Errorf("SynthesizeReader() = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,97 @@
+package tts
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsRecorder receives the outcome of every session of the synthesizers configured with
+// WithMetricsRecorder, to feed a monitoring system without this package depending on it.
+// Its methods are called from the session goroutines, concurrently for concurrent sessions.
+// An adapter to Prometheus could be:
+//
+//	type promRecorder struct {
+//		sessions, audioBytes prometheus.Counter
+//		errors               *prometheus.CounterVec // label "code"
+//		duration, ttfb       prometheus.Histogram
+//	}
+//
+//	func (r *promRecorder) SessionStarted() { r.sessions.Inc() }
+//
+//	func (r *promRecorder) SessionEnded(m tts.SessionMetrics) {
+//		r.duration.Observe(m.Duration.Seconds())
+//		r.audioBytes.Add(float64(m.AudioBytes))
+//		if m.TimeToFirstAudio > 0 {
+//			r.ttfb.Observe(m.TimeToFirstAudio.Seconds())
+//		}
+//		if m.Err != nil {
+//			r.errors.WithLabelValues(strconv.Itoa(m.Code)).Inc()
+//		}
+//	}
+type MetricsRecorder interface {
+	// SessionStarted is called when a session starts, after Prepare connected
+	SessionStarted()
+	// SessionEnded is called once the events of the session are all dispatched, and when
+	// Prepare fails to connect, without SessionStarted, e.g. on a rejected signature
+	SessionEnded(SessionMetrics)
+}
+
+// SessionMetrics is the outcome of a session passed to MetricsRecorder.SessionEnded
+type SessionMetrics struct {
+	Duration         time.Duration // start of Prepare to the end of the reading
+	AudioBytes       int64         // audio received
+	TimeToFirstAudio time.Duration // first Send to the first audio frame, zero without audio
+	Err              error         // first failure reported to OnSynthesisFail, else the error of Wait
+	Code             int           // server code of Err, zero when Err isn't a server error
+}
+
+// recordSessionEnd runs at the end of eventDispatch
+func (synthesizer *SpeechWsv2Synthesizer) recordSessionEnd() {
+	recorder := synthesizer.recorder
+	if recorder == nil {
+		return
+	}
+	detailed := synthesizer.DetailedMetrics()
+	m := SessionMetrics{
+		AudioBytes:       atomic.LoadInt64(&synthesizer.counters.audioBytes),
+		TimeToFirstAudio: detailed.TimeToFirstAudio,
+		Err:              synthesizer.sessionErr(),
+	}
+	if !detailed.StartedAt.IsZero() && !detailed.EndedAt.IsZero() {
+		m.Duration = detailed.EndedAt.Sub(detailed.StartedAt)
+	}
+	m.Code = errorCode(m.Err)
+	recorder.SessionEnded(m)
+}
+
+// recordPrepareFailure reports a Prepare failing to connect, so that the sessions the
+// server refused are counted
+func (synthesizer *SpeechWsv2Synthesizer) recordPrepareFailure(err error) {
+	recorder := synthesizer.recorder
+	if recorder == nil {
+		return
+	}
+	m := SessionMetrics{Err: err, Code: errorCode(err)}
+	if startedAt := synthesizer.DetailedMetrics().StartedAt; !startedAt.IsZero() {
+		m.Duration = time.Since(startedAt)
+	}
+	recorder.SessionEnded(m)
+}
+
+// errorCode is the server code of err, zero when err isn't a server error
+func errorCode(err error) int {
+	var synthesisErr *SynthesisError
+	if errors.As(err, &synthesisErr) {
+		return synthesisErr.Code
+	}
+	return 0
+}
+
+// sessionErr is the first failure dispatched, else the error of Wait
+func (synthesizer *SpeechWsv2Synthesizer) sessionErr() error {
+	if synthesizer.failure != nil {
+		return synthesizer.failure
+	}
+	return synthesizer.terminationErr()
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// countingRecorder counts the sessions reported to it
+type countingRecorder struct {
+	mutex   sync.Mutex
+	started int
+	ended   []SessionMetrics
+}
+
+func (r *countingRecorder) SessionStarted() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.started++
+}
+
+func (r *countingRecorder) SessionEnded(m SessionMetrics) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ended = append(r.ended, m)
+}
+
+func (r *countingRecorder) counts() (int, []SessionMetrics) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.started, append([]SessionMetrics(nil), r.ended...)
+}
+
+func TestMetricsRecorderSession(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, pcm(320), pcm(160))
+	})
+	recorder := &countingRecorder{}
+	for i := 0; i < 2; i++ {
+		s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithMetricsRecorder(recorder))
+		if err := s.Prepare(); err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if err := s.Send("你好"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Complete(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Wait(); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	started, ended := recorder.counts()
+	if started != 2 || len(ended) != 2 {
+		t.Fatalf("%d sessions started and %d ended, want 2 and 2", started, len(ended))
+	}
+	for _, m := range ended {
+		if m.Err != nil || m.Code != 0 || m.AudioBytes != 480 || m.Duration <= 0 {
+			t.Errorf("SessionEnded(%+v), want 480 bytes of audio and no error", m)
+		}
+	}
+}
+
+func TestMetricsRecorderPrepareFailure(t *testing.T) {
+	for _, code := range []int{4002, 4006} {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			mockServer(t, func(conn *websocket.Conn) {
+				conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"code":%d,"message":"refused"}`, code)))
+				drain(conn)
+			})
+			recorder := &countingRecorder{}
+			s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithMetricsRecorder(recorder))
+			if err := s.Prepare(); err == nil {
+				t.Fatal("Prepare() error = nil")
+			}
+			started, ended := recorder.counts()
+			if started != 0 {
+				t.Errorf("SessionStarted called %d times, want none", started)
+			}
+			if len(ended) != 1 || ended[0].Err == nil || ended[0].Code != code {
+				t.Fatalf("SessionEnded got %+v, want one call with code %d", ended, code)
+			}
+		})
+	}
+}
+
+func TestMetricsRecorderPrepareValidation(t *testing.T) {
+	recorder := &countingRecorder{}
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithMetricsRecorder(recorder))
+	if err := s.Prepare(); err == nil {
+		t.Fatal("Prepare() error = nil without credential")
+	}
+	// refused before connecting, not a session
+	if started, ended := recorder.counts(); started != 0 || len(ended) != 0 {
+		t.Errorf("%d sessions started and %d ended, want none", started, len(ended))
+	}
+}
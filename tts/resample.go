@@ -0,0 +1,74 @@
+package tts
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Resampler is a Transcoder converting 16-bit mono pcm from From Hz to To Hz, e.g. the
+// 16 kHz of a voice to the 8 kHz of telephony, by linear interpolation. It is cheap and
+// delays the audio by one sample only, but doesn't low-pass filter: downsampling folds the
+// frequencies above the new Nyquist back as a faint aliasing, mostly on sibilants, which
+// 8 kHz speech usually tolerates. Use a polyphase resampler of a DSP library when
+// quality matters more than cost.
+type Resampler struct {
+	From, To int64
+
+	odd     []byte // last byte of an odd length Write
+	prev    int16  // last sample of the previous Write
+	hasPrev bool
+	pos     float64 // source position of the next output sample, relative to prev
+}
+
+// NewResampler creates instance of Resampler
+func NewResampler(from, to int64) (*Resampler, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("invalid sample rates %d to %d", from, to)
+	}
+	return &Resampler{From: from, To: to}, nil
+}
+
+// Write returns the resampled pcm, output samples whose neighbours haven't arrived yet
+// are computed on the next Write
+func (r *Resampler) Write(pcm []byte) ([]byte, error) {
+	data := append(r.odd, pcm...)
+	r.odd = nil
+	if len(data)%2 == 1 {
+		r.odd = []byte{data[len(data)-1]}
+		data = data[:len(data)-1]
+	}
+	samples := make([]int16, 0, len(data)/2+1)
+	if r.hasPrev {
+		samples = append(samples, r.prev)
+	}
+	for i := 0; i < len(data); i += 2 {
+		samples = append(samples, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	if r.From == r.To {
+		r.prev, r.hasPrev = samples[len(samples)-1], true
+		return data, nil
+	}
+	step := float64(r.From) / float64(r.To)
+	last := float64(len(samples) - 1)
+	out := make([]byte, 0, int(last/step+1)*2)
+	for ; r.pos < last; r.pos += step {
+		i := int(r.pos)
+		frac := r.pos - float64(i)
+		v := float64(samples[i])*(1-frac) + float64(samples[i+1])*frac
+		out = append(out, 0, 0)
+		binary.LittleEndian.PutUint16(out[len(out)-2:], uint16(int16(math.Round(v))))
+	}
+	r.pos -= last
+	r.prev, r.hasPrev = samples[len(samples)-1], true
+	return out, nil
+}
+
+// Flush resets the Resampler for another stream, the pending sample is dropped
+func (r *Resampler) Flush() ([]byte, error) {
+	r.odd, r.prev, r.hasPrev, r.pos = nil, 0, false, 0
+	return nil, nil
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+const sineAmplitude = 10000
+
+// sine returns n samples of a 440 Hz sine at rate Hz as 16-bit pcm
+func sine(rate int64, n int) []byte {
+	data := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		v := sineAmplitude * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(math.Round(v))))
+	}
+	return data
+}
+
+func TestResamplerSine(t *testing.T) {
+	const from, n = 16000, 16000
+	input := sine(from, n)
+	for _, to := range []int64{8000, 24000, 16000} {
+		r, err := NewResampler(from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []byte
+		// uneven writes, some splitting a sample
+		for i, size := 0, 1; i < len(input); i, size = i+size, size%997+331 {
+			end := i + size
+			if end > len(input) {
+				end = len(input)
+			}
+			chunk, err := r.Write(input[i:end])
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, chunk...)
+		}
+
+		want := float64(n) * float64(to) / float64(from)
+		if got := float64(len(out) / 2); math.Abs(got-want) > 2 {
+			t.Errorf("%d Hz: %g samples, want about %g, the rate ratio", to, got, want)
+		}
+		// linear interpolation of a 440 Hz sine errs by well under 1% of its amplitude
+		expected := sine(to, len(out)/2)
+		maxErr := 0.0
+		for i := 0; i+1 < len(out); i += 2 {
+			d := float64(int16(binary.LittleEndian.Uint16(out[i:]))) - float64(int16(binary.LittleEndian.Uint16(expected[i:])))
+			maxErr = math.Max(maxErr, math.Abs(d))
+		}
+		if maxErr > sineAmplitude/100 {
+			t.Errorf("%d Hz: samples off the sine by up to %g", to, maxErr)
+		}
+	}
+}
+
+func TestNewResamplerInvalid(t *testing.T) {
+	if _, err := NewResampler(0, 8000); err == nil {
+		t.Error("NewResampler(0, 8000) error = nil")
+	}
+	if _, err := NewResampler(16000, -1); err == nil {
+		t.Error("NewResampler(16000, -1) error = nil")
+	}
+}
@@ -0,0 +1,111 @@
+package tts
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// SeekableAudioBuffer keeps all the audio written to it in memory for previews or
+// scrubbing: use it as AudioWriter and read it back from any offset, even while the
+// synthesis is still writing. It holds the whole synthesis, about 32KB per second of
+// 16 kHz pcm, until it is dropped.
+type SeekableAudioBuffer struct {
+	// SampleRate of the 16-bit mono pcm held, used by Duration and Offset
+	SampleRate int64
+
+	mutex sync.RWMutex
+	data  []byte
+	pos   int64
+}
+
+// NewSeekableAudioBuffer creates instance of SeekableAudioBuffer
+func NewSeekableAudioBuffer(sampleRate int64) *SeekableAudioBuffer {
+	return &SeekableAudioBuffer{SampleRate: sampleRate}
+}
+
+// Write appends p, the read position is not moved
+func (b *SeekableAudioBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// ReadAt reads from the absolute offset off, returning io.EOF past the audio written so far
+func (b *SeekableAudioBuffer) ReadAt(p []byte, off int64) (int, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if off < 0 {
+		return 0, errors.New("SeekableAudioBuffer.ReadAt: negative offset")
+	}
+	if off >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read reads from the read position and advances it
+func (b *SeekableAudioBuffer) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.pos >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[b.pos:])
+	b.pos += int64(n)
+	return n, nil
+}
+
+// Seek moves the read position as io.Seeker does, io.SeekEnd being relative to the audio
+// written so far
+func (b *SeekableAudioBuffer) Seek(offset int64, whence int) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	default:
+		return 0, errors.New("SeekableAudioBuffer.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("SeekableAudioBuffer.Seek: negative position")
+	}
+	b.pos = offset
+	return offset, nil
+}
+
+// Len returns the bytes written so far
+func (b *SeekableAudioBuffer) Len() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.data)
+}
+
+// Bytes returns a copy of the audio written so far
+func (b *SeekableAudioBuffer) Bytes() []byte {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]byte(nil), b.data...)
+}
+
+// Duration returns the playout duration of the audio written so far
+func (b *SeekableAudioBuffer) Duration() time.Duration {
+	if b.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(b.Len()) * time.Second / time.Duration(b.SampleRate*2)
+}
+
+// Offset returns the byte offset of the sample played at d, to Seek or ReadAt from
+func (b *SeekableAudioBuffer) Offset(d time.Duration) int64 {
+	return int64(d) * b.SampleRate / int64(time.Second) * 2
+}
@@ -0,0 +1,55 @@
+package tts
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestSeekableAudioBufferAsAudioWriter(t *testing.T) {
+	audio := pcm(6400)
+	conn := newFakeConn()
+	buf := NewSeekableAudioBuffer(16000)
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.AudioWriter = buf
+	startFake(s, conn)
+	for i := 0; i < len(audio); i += 1600 {
+		conn.binary(audio[i : i+1600])
+	}
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), audio) {
+		t.Fatalf("buffer holds %d bytes, want the %d written", buf.Len(), len(audio))
+	}
+	if d := buf.Duration(); d != 200*time.Millisecond {
+		t.Errorf("Duration() = %v, want 200ms", d)
+	}
+
+	off := buf.Offset(100 * time.Millisecond)
+	if off != 3200 {
+		t.Fatalf("Offset(100ms) = %d, want 3200", off)
+	}
+	p := make([]byte, 100)
+	if n, err := buf.ReadAt(p, off); n != len(p) || err != nil || !bytes.Equal(p, audio[off:off+100]) {
+		t.Errorf("ReadAt(%d) = %d, %v, want the bytes from there", off, n, err)
+	}
+	if n, err := buf.ReadAt(p, int64(len(audio))-10); n != 10 || err != io.EOF {
+		t.Errorf("ReadAt() near the end = %d, %v, want 10, EOF", n, err)
+	}
+
+	if pos, err := buf.Seek(-1000, io.SeekEnd); err != nil || pos != int64(len(audio))-1000 {
+		t.Fatalf("Seek(-1000, SeekEnd) = %d, %v", pos, err)
+	}
+	rest, err := ioutil.ReadAll(buf)
+	if err != nil || !bytes.Equal(rest, audio[len(audio)-1000:]) {
+		t.Errorf("read %d bytes after Seek, %v, want the last 1000", len(rest), err)
+	}
+	if _, err := buf.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek(-1, SeekStart) error = nil")
+	}
+}
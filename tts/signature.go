@@ -0,0 +1,33 @@
+package tts
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+)
+
+// SignatureAlgorithm selects the HMAC hash used to sign websocket requests
+type SignatureAlgorithm int
+
+const (
+	// SignatureHmacSHA1 is the default, accepted by every deployment of the service
+	SignatureHmacSHA1 SignatureAlgorithm = iota
+	// SignatureHmacSHA256 adds SignatureMethod=HmacSHA256 to the signed query, only use it
+	// against endpoints supporting SHA256 signing
+	SignatureHmacSHA256
+)
+
+func (a SignatureAlgorithm) hash() func() hash.Hash {
+	if a == SignatureHmacSHA256 {
+		return sha256.New
+	}
+	return sha1.New
+}
+
+// method is the SignatureMethod query value, empty for the default algorithm
+func (a SignatureAlgorithm) method() string {
+	if a == SignatureHmacSHA256 {
+		return "HmacSHA256"
+	}
+	return ""
+}
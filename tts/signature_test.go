@@ -0,0 +1,242 @@
+package tts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+func TestSignatureAlgorithms(t *testing.T) {
+	const key = "topsecretkey"
+	const signURL = "tts.cloud.tencent.com/stream_wsv2?Action=TextToStreamAudioWSv2&AppId=1300000000"
+	expected := func(h func() hash.Hash) string {
+		mac := hmac.New(h, []byte(key))
+		mac.Write([]byte("GET" + signURL))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	sha1Sig := (&SpeechWsv2Synthesizer{SignatureAlgorithm: SignatureHmacSHA1}).genWsSignature(signURL, key)
+	sha256Sig := (&SpeechWsv2Synthesizer{SignatureAlgorithm: SignatureHmacSHA256}).genWsSignature(signURL, key)
+	if want := expected(sha1.New); sha1Sig != want {
+		t.Errorf("HMAC-SHA1 signature = %q, want %q", sha1Sig, want)
+	}
+	if want := expected(sha256.New); sha256Sig != want {
+		t.Errorf("HMAC-SHA256 signature = %q, want %q", sha256Sig, want)
+	}
+	if sha1Sig == sha256Sig {
+		t.Error("both algorithms gave the same signature")
+	}
+}
+
+func TestSignatureMethodInQuery(t *testing.T) {
+	tests := []struct {
+		algorithm SignatureAlgorithm
+		want      string
+	}{
+		{SignatureHmacSHA1, ""},
+		{SignatureHmacSHA256, "HmacSHA256"},
+	}
+	for _, tt := range tests {
+		s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+		s.SignatureAlgorithm = tt.algorithm
+		signed, err := s.BuildSignedURL()
+		if err != nil {
+			t.Fatalf("BuildSignedURL() error = %v", err)
+		}
+		u, _ := url.Parse(signed)
+		if got := u.Query().Get("SignatureMethod"); got != tt.want {
+			t.Errorf("algorithm %d: SignatureMethod = %q, want %q", tt.algorithm, got, tt.want)
+		}
+	}
+}
+
+func TestInjectedSignatureReused(t *testing.T) {
+	fixedNow(t, time.Unix(1600000000, 0))
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+		drain(conn)
+	})
+
+	credential := common.NewCredential("AKIDexample", "")
+	for i := 0; i < 2; i++ {
+		s := NewSpeechWsv2Synthesizer(0, credential, &recordListener{}, WithSignature("presigned==", 1600000000, 1600003600))
+		s.Text = "你好"
+		if err := s.Prepare(); err != nil {
+			t.Fatalf("Prepare() #%d error = %v", i+1, err)
+		}
+		if err := s.Wait(); err != nil {
+			t.Fatalf("Wait() #%d error = %v", i+1, err)
+		}
+		requests := server.requests()
+		if got := requests[len(requests)-1].URL.Query().Get("Signature"); got != "presigned==" {
+			t.Errorf("session #%d signed with %q, want the injected signature", i+1, got)
+		}
+		if s.LastSignature() != "presigned==" || !s.LastSignedAt().Equal(time.Unix(1600000000, 0)) {
+			t.Errorf("LastSignature(), LastSignedAt() = %q, %v, want the injected ones", s.LastSignature(), s.LastSignedAt())
+		}
+	}
+}
+
+func TestInjectedSignatureExpired(t *testing.T) {
+	fixedNow(t, time.Unix(1600003600, 0))
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithSignature("presigned==", 1600000000, 1600003600))
+	if err := s.Validate(); err == nil {
+		t.Error("Validate() = nil, want an error for the expired signature")
+	}
+}
+
+func TestQueryMutatorSigned(t *testing.T) {
+	fixedNow(t, time.Unix(1600000000, 0))
+	mutate := func(query map[string]string) {
+		query["Experimental"] = "on"
+		query["Codec"] = "mp3"
+	}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithQueryMutator(mutate))
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	u, _ := url.Parse(signed)
+	query := u.Query()
+	if query.Get("Experimental") != "on" || query.Get("Codec") != "mp3" {
+		t.Errorf("query %v, want the keys added and overridden by the mutator", query)
+	}
+	plain := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	plain.SessionId = s.SessionId
+	plainURL, _ := plain.BuildSignedURL()
+	plainQuery, _ := url.Parse(plainURL)
+	if query.Get("Signature") == plainQuery.Query().Get("Signature") {
+		t.Error("the mutated parameters did not change the signature")
+	}
+	if got, want := query.Get("Signature"), s.genWsSignature(s.buildURL(false), testCredential.SecretKey); got != want {
+		t.Errorf("Signature = %q, want %q over the mutated parameters", got, want)
+	}
+}
+
+// skewedClock makes Now advance by a minute per call, as a clock being corrected
+func skewedClock(t *testing.T) {
+	previous := Now
+	var calls int64
+	Now = func() time.Time {
+		return time.Unix(1600000000+60*atomic.AddInt64(&calls, 1), 0)
+	}
+	t.Cleanup(func() { Now = previous })
+}
+
+func TestSignatureRetryAfterRejection(t *testing.T) {
+	skewedClock(t)
+	var attempts int32
+	server := mockServer(t, func(conn *websocket.Conn) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4002,"message":"signature expired"}`))
+			drain(conn)
+			return
+		}
+		handshake(conn)
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithSignatureRetry())
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v, want the retry to succeed", err)
+	}
+	defer s.Abort()
+	reqs := server.requests()
+	if len(reqs) != 2 {
+		t.Fatalf("%d requests, want the rejected one and the retry", len(reqs))
+	}
+	first, retry := reqs[0].URL.Query(), reqs[1].URL.Query()
+	if first.Get("Timestamp") == retry.Get("Timestamp") || first.Get("Signature") == retry.Get("Signature") {
+		t.Errorf("retry sent Timestamp %s and Signature %s again, want them fresh", retry.Get("Timestamp"), retry.Get("Signature"))
+	}
+}
+
+func TestSignatureRetryAfterReadyTimeout(t *testing.T) {
+	var attempts int32
+	server := mockServer(t, func(conn *websocket.Conn) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// the handshake response, then no ready frame
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success"}`))
+			drain(conn)
+			return
+		}
+		handshake(conn)
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithSignatureRetry())
+	s.PrepareTimeout = 100 * time.Millisecond
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v, want the retry to succeed", err)
+	}
+	defer s.Abort()
+	if n := len(server.requests()); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestSignatureRejectedWithoutRetry(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4002,"message":"signature expired"}`))
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := s.Prepare(); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("Prepare() error = %v, want %v", err, ErrAuthFailed)
+	}
+	if n := len(server.requests()); n != 1 {
+		t.Errorf("%d requests, want no retry", n)
+	}
+}
+
+func TestVerifyCredential(t *testing.T) {
+	frames := make(chan int, 2) // the check, then Prepare
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		n := 0
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+			n++
+		}
+		frames <- n
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := s.VerifyCredential(context.Background()); err != nil {
+		t.Fatalf("VerifyCredential() error = %v", err)
+	}
+	select {
+	case n := <-frames:
+		if n != 0 {
+			t.Errorf("%d frames sent by VerifyCredential, want none", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("VerifyCredential() left the connection open")
+	}
+	// the synthesizer is left ready for Prepare
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() after VerifyCredential error = %v", err)
+	}
+	s.Abort()
+}
+
+func TestVerifyCredentialRejected(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":4002,"message":"auth failed"}`))
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, common.NewCredential("AKIDwrong", "secret"), &recordListener{})
+	if err := s.VerifyCredential(context.Background()); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("VerifyCredential() error = %v, want %v", err, ErrAuthFailed)
+	}
+}
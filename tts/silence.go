@@ -0,0 +1,105 @@
+package tts
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// silenceWindow is the span over which TrimSilence measures the energy
+const silenceWindow = 10 // ms
+
+// TrimSilence returns the part of 16-bit mono pcm between the first and the last 10 ms
+// window louder than thresholdDB, in dB relative to full scale (e.g. -50), or nil when
+// all of it is quieter. The energy is the RMS of a window, so the cuts fall on window
+// boundaries and keep up to 10 ms of silence; a soft onset such as a breath below the
+// threshold is cut too. The result shares the memory of pcm.
+func TrimSilence(pcm []byte, sampleRate int64, thresholdDB float64) []byte {
+	window := silenceWindowBytes(sampleRate)
+	pcm = pcm[:len(pcm)/2*2]
+	begin := -1
+	end := 0
+	for i := 0; i < len(pcm); i += window {
+		j := i + window
+		if j > len(pcm) {
+			j = len(pcm)
+		}
+		if !isSilent(pcm[i:j], thresholdDB) {
+			if begin < 0 {
+				begin = i
+			}
+			end = j
+		}
+	}
+	if begin < 0 {
+		return nil
+	}
+	return pcm[begin:end]
+}
+
+func silenceWindowBytes(sampleRate int64) int {
+	n := int(sampleRate) * silenceWindow / 1000 * 2
+	if n < 2 {
+		n = 2
+	}
+	return n
+}
+
+// isSilent reports whether the RMS of the 16-bit samples of pcm is below thresholdDB
+func isSilent(pcm []byte, thresholdDB float64) bool {
+	n := len(pcm) / 2
+	if n == 0 {
+		return true
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+		sum += v * v
+	}
+	rms := math.Sqrt(sum/float64(n)) / 32768
+	return rms == 0 || 20*math.Log10(rms) < thresholdDB
+}
+
+// LeadingSilenceTrimmer is a Transcoder dropping the leading silence of 16-bit mono pcm as
+// TrimSilence does, streaming: once a window louder than ThresholdDB arrived, the audio
+// passes through untouched. Trailing silence can't be detected without buffering the
+// whole audio, use TrimSilence on the complete pcm for it.
+type LeadingSilenceTrimmer struct {
+	SampleRate  int64
+	ThresholdDB float64
+
+	pending []byte // incomplete window
+	started bool
+}
+
+// NewLeadingSilenceTrimmer creates instance of LeadingSilenceTrimmer
+func NewLeadingSilenceTrimmer(sampleRate int64, thresholdDB float64) *LeadingSilenceTrimmer {
+	return &LeadingSilenceTrimmer{SampleRate: sampleRate, ThresholdDB: thresholdDB}
+}
+
+// Write returns pcm from the first loud window on, nothing before it
+func (t *LeadingSilenceTrimmer) Write(pcm []byte) ([]byte, error) {
+	if t.started {
+		return pcm, nil
+	}
+	data := append(t.pending, pcm...)
+	t.pending = nil
+	window := silenceWindowBytes(t.SampleRate)
+	for i := 0; i+window <= len(data); i += window {
+		if !isSilent(data[i:i+window], t.ThresholdDB) {
+			t.started = true
+			return data[i:], nil
+		}
+	}
+	t.pending = data[len(data)/window*window:]
+	return nil, nil
+}
+
+// Flush returns the last incomplete window if loud, and resets the trimmer
+func (t *LeadingSilenceTrimmer) Flush() ([]byte, error) {
+	var out []byte
+	if !t.started && !isSilent(t.pending, t.ThresholdDB) {
+		out = t.pending
+	}
+	t.pending, t.started = nil, false
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package tts
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrimSilence(t *testing.T) {
+	voice := sine(16000, 1600) // 100 ms
+	clip := append(append(make([]byte, 6400), voice...), make([]byte, 9600)...)
+	if got := TrimSilence(clip, 16000, -50); !bytes.Equal(got, voice) {
+		t.Errorf("TrimSilence() = %d bytes, want the %d bytes of voice between 200 ms and 300 ms of zeros", len(got), len(voice))
+	}
+	if got := TrimSilence(make([]byte, 3200), 16000, -50); got != nil {
+		t.Errorf("TrimSilence() of zeros = %d bytes, want nil", len(got))
+	}
+	// a threshold above the level of the voice cuts it all
+	if got := TrimSilence(clip, 16000, 0); got != nil {
+		t.Errorf("TrimSilence() at 0 dB = %d bytes, want nil", len(got))
+	}
+}
+
+func TestLeadingSilenceTrimmer(t *testing.T) {
+	voice := sine(16000, 1600)
+	clip := append(append(make([]byte, 6400), voice...), make([]byte, 3200)...)
+	tc := NewLeadingSilenceTrimmer(16000, -50)
+	var out []byte
+	for i := 0; i < len(clip); i += 999 {
+		end := i + 999
+		if end > len(clip) {
+			end = len(clip)
+		}
+		data, err := tc.Write(clip[i:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, data...)
+	}
+	flushed, err := tc.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = append(out, flushed...)
+	// the head is trimmed, the trailing silence passes through
+	if want := clip[6400:]; !bytes.Equal(out, want) {
+		t.Errorf("trimmer output = %d bytes, want the %d bytes from the voice on", len(out), len(want))
+	}
+}
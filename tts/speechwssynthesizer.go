@@ -54,7 +54,7 @@ type SynthesisSubtitle struct {
 // SpeechWsSynthesizer is the entry for TTS websocket service
 type SpeechWsSynthesizer struct {
 	Credential       *common.Credential
-	action           string  `json:"Action"`
+	action           string
 	AppID            int64   `json:"AppId"`
 	Timestamp        int64   `json:"Timestamp"`
 	Expired          int64   `json:"Expired"`
@@ -0,0 +1,83 @@
+package tts
+
+import "sync"
+
+// wsv2Collector keeps the audio and the subtitles received during a session, subtitles
+// re-sent by the server are merged by BeginIndex
+type wsv2Collector struct {
+	mutex     sync.Mutex
+	audio     []byte
+	subtitles []Synthesisv2Subtitle
+	index     map[int]int
+	appended  int // subtitles already passed to OnSubtitleAppended
+}
+
+func (c *wsv2Collector) addAudio(data []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.audio = append(c.audio, data...)
+}
+
+func (c *wsv2Collector) addSubtitles(subtitles []Synthesisv2Subtitle) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.index == nil {
+		c.index = make(map[int]int)
+	}
+	for _, sub := range subtitles {
+		if i, ok := c.index[sub.BeginIndex]; ok {
+			c.subtitles[i] = sub
+			continue
+		}
+		c.index[sub.BeginIndex] = len(c.subtitles)
+		c.subtitles = append(c.subtitles, sub)
+	}
+}
+
+// finalized returns the subtitles not yet appended which the server won't revise anymore:
+// all but the latest one, or all of them once the synthesis is final
+func (c *wsv2Collector) finalized(final bool) []Synthesisv2Subtitle {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	end := len(c.subtitles)
+	if !final {
+		end--
+	}
+	if end <= c.appended {
+		return nil
+	}
+	subtitles := make([]Synthesisv2Subtitle, end-c.appended)
+	copy(subtitles, c.subtitles[c.appended:end])
+	c.appended = end
+	return subtitles
+}
+
+func (c *wsv2Collector) snapshot() ([]byte, []Synthesisv2Subtitle) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	audio := make([]byte, len(c.audio))
+	copy(audio, c.audio)
+	subtitles := make([]Synthesisv2Subtitle, len(c.subtitles))
+	copy(subtitles, c.subtitles)
+	return audio, subtitles
+}
+
+// Subtitles returns the subtitles received so far, merged by BeginIndex, or none with
+// WithoutSubtitleAccumulation
+func (synthesizer *SpeechWsv2Synthesizer) Subtitles() []Synthesisv2Subtitle {
+	_, subtitles := synthesizer.collector.snapshot()
+	return subtitles
+}
+
+// StopAndCollect ends the session at once, e.g. on barge-in, and returns the audio and
+// subtitles received up to that point. No failure is reported to the listener. The audio
+// is only kept with WithCollect, at the cost of a copy of all of it in memory; without it
+// StopAndCollect returns no audio.
+func (synthesizer *SpeechWsv2Synthesizer) StopAndCollect() ([]byte, []Synthesisv2Subtitle) {
+	if synthesizer.started {
+		synthesizer.shutdown(nil)
+		<-synthesizer.receiveEnd
+		<-synthesizer.eventEnd
+	}
+	return synthesizer.collector.snapshot()
+}
@@ -0,0 +1,127 @@
+package tts
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStopAndCollectMidStream(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithCollect())
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`)
+	conn.binary(pcm(160))
+	waitFor(t, "frames", func() bool { return listener.count("audio") == 2 })
+
+	audio, subtitles := s.StopAndCollect()
+	if !bytes.Equal(audio, append(pcm(320), pcm(160)...)) {
+		t.Errorf("StopAndCollect() audio is %d bytes, want the 480 bytes received", len(audio))
+	}
+	if len(subtitles) != 1 || subtitles[0].Text != "你" {
+		t.Errorf("StopAndCollect() subtitles = %+v, want the one received", subtitles)
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Errorf("OnSynthesisFail called with %v, want no failure", failures)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want nil after StopAndCollect", err)
+	}
+	if s.WasComplete() {
+		t.Error("WasComplete() = true, want the synthesis reported truncated")
+	}
+}
+
+func TestStopAndCollectWithoutCollect(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`)
+	waitFor(t, "frames", func() bool { return listener.count("text") == 1 })
+
+	audio, subtitles := s.StopAndCollect()
+	if len(audio) != 0 {
+		t.Errorf("StopAndCollect() audio is %d bytes, want none kept without WithCollect", len(audio))
+	}
+	if len(subtitles) != 1 {
+		t.Errorf("StopAndCollect() subtitles = %+v, want the one received", subtitles)
+	}
+	if got := listener.audioBytes(); !bytes.Equal(got, pcm(320)) {
+		t.Errorf("OnAudioResult got %d bytes, want the 320 bytes received", len(got))
+	}
+}
+
+// appendListener records the subtitles passed to OnSubtitleAppended
+type appendListener struct {
+	recordListener
+	appended []Synthesisv2Subtitle
+}
+
+func (l *appendListener) OnSubtitleAppended(sub Synthesisv2Subtitle) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.appended = append(l.appended, sub)
+}
+
+func TestSubtitleAppendedOncePerSubtitle(t *testing.T) {
+	conn := newFakeConn()
+	listener := &appendListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	// cumulative frames, the last subtitle of each being revised by the next one
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":150}]}}`)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160},{"Text":"好","BeginIndex":1,"EndIndex":2,"BeginTime":160,"EndTime":300}]}}`)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160},{"Text":"好","BeginIndex":1,"EndIndex":2,"BeginTime":160,"EndTime":320},{"Text":"啊","BeginIndex":2,"EndIndex":3,"BeginTime":320,"EndTime":480}]}}`)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	listener.mutex.Lock()
+	appended := listener.appended
+	listener.mutex.Unlock()
+	want := []Synthesisv2Subtitle{
+		{Text: "你", BeginIndex: 0, EndIndex: 1, BeginTime: 0, EndTime: 160},
+		{Text: "好", BeginIndex: 1, EndIndex: 2, BeginTime: 160, EndTime: 320},
+		{Text: "啊", BeginIndex: 2, EndIndex: 3, BeginTime: 320, EndTime: 480},
+	}
+	if len(appended) != len(want) {
+		t.Fatalf("OnSubtitleAppended got %+v, want %+v", appended, want)
+	}
+	for i := range want {
+		if appended[i] != want[i] {
+			t.Errorf("OnSubtitleAppended #%d got %+v, want %+v", i, appended[i], want[i])
+		}
+	}
+	if n := listener.count("text"); n != 3 {
+		t.Errorf("OnTextResult called %d times, want once per frame", n)
+	}
+}
+
+func TestWithoutSubtitleAccumulation(t *testing.T) {
+	conn := newFakeConn()
+	listener := &appendListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener, WithoutSubtitleAccumulation())
+	s.EnableSubtitle = true
+	startFake(s, conn)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"好","BeginIndex":1,"EndIndex":2,"BeginTime":160,"EndTime":320}]}}`)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if subtitles := s.Subtitles(); len(subtitles) != 0 {
+		t.Errorf("Subtitles() = %+v, want none without accumulation", subtitles)
+	}
+	listener.mutex.Lock()
+	texts, appended := listener.texts, listener.appended
+	listener.mutex.Unlock()
+	if len(texts) != 2 || texts[0].Result.Subtitles[0].Text != "你" || texts[1].Result.Subtitles[0].Text != "好" {
+		t.Errorf("OnTextResult got %d frames, want each frame with its subtitles", len(texts))
+	}
+	if len(appended) != 0 {
+		t.Errorf("OnSubtitleAppended got %+v, want no call", appended)
+	}
+}
@@ -0,0 +1,165 @@
+package tts
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// DetailedMetrics splits the latency of a SpeechWsv2Synthesizer session between
+// network and server side processing. The wall clock times of its phases, to build
+// timelines, are zero for the phases not reached, e.g. HandshakeAt when the dial failed.
+type DetailedMetrics struct {
+	StartedAt   time.Time // Prepare started connecting, or ReplayFrames started
+	HandshakeAt time.Time // handshake response read
+	EndedAt     time.Time // the session stopped reading, whatever the reason
+
+	HandshakeRTT     time.Duration // dial until the handshake response is read
+	TimeToReady      time.Duration // handshake response until the ready frame, server side preparation
+	TimeToFirstAudio time.Duration // first Send until the first audio frame
+	FrameCount       int           // frames received after ready
+	MaxFrameGap      time.Duration // longest gap between two frames
+	AvgFrameGap      time.Duration // mean gap between two frames
+}
+
+type wsv2Timing struct {
+	startedAt    time.Time
+	endedAt      time.Time
+	dialAt       time.Time
+	handshakeAt  time.Time
+	readyAt      time.Time
+	firstSendAt  time.Time
+	firstAudioAt time.Time
+	lastFrameAt  time.Time
+	frameCount   int
+	totalGap     time.Duration
+	maxGap       time.Duration
+}
+
+// DetailedMetrics returns the latency breakdown measured so far, safe to call during synthesis
+func (synthesizer *SpeechWsv2Synthesizer) DetailedMetrics() DetailedMetrics {
+	synthesizer.metricsMutex.Lock()
+	defer synthesizer.metricsMutex.Unlock()
+	t := synthesizer.timing
+	m := DetailedMetrics{
+		StartedAt:   t.startedAt,
+		HandshakeAt: t.handshakeAt,
+		EndedAt:     t.endedAt,
+		FrameCount:  t.frameCount,
+		MaxFrameGap: t.maxGap,
+	}
+	if !t.handshakeAt.IsZero() {
+		m.HandshakeRTT = t.handshakeAt.Sub(t.dialAt)
+	}
+	if !t.readyAt.IsZero() {
+		m.TimeToReady = t.readyAt.Sub(t.handshakeAt)
+	}
+	if !t.firstSendAt.IsZero() && !t.firstAudioAt.IsZero() {
+		m.TimeToFirstAudio = t.firstAudioAt.Sub(t.firstSendAt)
+	}
+	if t.frameCount > 1 {
+		m.AvgFrameGap = t.totalGap / time.Duration(t.frameCount-1)
+	}
+	return m
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) recordTiming(f func(t *wsv2Timing, now time.Time)) {
+	synthesizer.metricsMutex.Lock()
+	defer synthesizer.metricsMutex.Unlock()
+	f(&synthesizer.timing, time.Now())
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) recordFrame(audio bool) {
+	synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) {
+		if !t.lastFrameAt.IsZero() {
+			gap := now.Sub(t.lastFrameAt)
+			t.totalGap += gap
+			if gap > t.maxGap {
+				t.maxGap = gap
+			}
+		}
+		t.lastFrameAt = now
+		t.frameCount++
+		if audio && t.firstAudioAt.IsZero() {
+			t.firstAudioAt = now
+		}
+	})
+}
+
+// Stats is a live snapshot of the counters of a SpeechWsv2Synthesizer session, unlike
+// DetailedMetrics it is meant to be polled while the synthesis is running
+type Stats struct {
+	AudioFrames   int64
+	TextFrames    int64
+	BytesReceived int64
+	Errors        int64
+	Dropped       int64 // events discarded by the DropPolicy
+	Overflowed    int64 // events that waited for room in the full event queue
+}
+
+// wsv2Counters is allocated separately to keep the 64-bit atomics aligned on 32-bit platforms
+type wsv2Counters struct {
+	audioFrames   int64
+	audioBytes    int64
+	textFrames    int64
+	bytesReceived int64
+	errors        int64
+	sentChars     int64
+	dropped       int64
+	overflowed    int64
+	maxEndIndex   int64
+}
+
+// Stats returns the live counters, safe to call from any goroutine
+func (synthesizer *SpeechWsv2Synthesizer) Stats() Stats {
+	c := synthesizer.counters
+	return Stats{
+		AudioFrames:   atomic.LoadInt64(&c.audioFrames),
+		TextFrames:    atomic.LoadInt64(&c.textFrames),
+		BytesReceived: atomic.LoadInt64(&c.bytesReceived),
+		Errors:        atomic.LoadInt64(&c.errors),
+		Dropped:       atomic.LoadInt64(&c.dropped),
+		Overflowed:    atomic.LoadInt64(&c.overflowed),
+	}
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) sentChars() int64 {
+	return atomic.LoadInt64(&synthesizer.counters.sentChars)
+}
+
+// recordProgress keeps the furthest EndIndex of subtitles, only receive writes it
+func (synthesizer *SpeechWsv2Synthesizer) recordProgress(subtitles []Synthesisv2Subtitle) {
+	c := synthesizer.counters
+	for _, sub := range subtitles {
+		if end := int64(sub.EndIndex); end > atomic.LoadInt64(&c.maxEndIndex) {
+			atomic.StoreInt64(&c.maxEndIndex, end)
+		}
+	}
+}
+
+// Progress returns the fraction, from 0 to 1, of the text sent so far that the server
+// reported as synthesized: the furthest subtitle EndIndex over the runes sent. It moves
+// only with EnableSubtitle, and is 1 once the final frame arrived. Text sent later lowers it.
+func (synthesizer *SpeechWsv2Synthesizer) Progress() float64 {
+	if synthesizer.getStatus() == eventTypeWsEndv2 {
+		return 1
+	}
+	sent := synthesizer.sentChars()
+	if sent == 0 {
+		return 0
+	}
+	return math.Min(1, float64(atomic.LoadInt64(&synthesizer.counters.maxEndIndex))/float64(sent))
+}
+
+// dispatchProgress calls OnProgress when Progress moved since the last call, it runs in
+// eventDispatch
+func (synthesizer *SpeechWsv2Synthesizer) dispatchProgress() {
+	l, ok := synthesizer.listener.(SpeechWsv2ProgressListener)
+	if !ok {
+		return
+	}
+	if progress := synthesizer.Progress(); progress != synthesizer.lastProgress {
+		synthesizer.lastProgress = progress
+		l.OnProgress(progress)
+	}
+}
@@ -0,0 +1,155 @@
+package tts
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDetailedMetricsHandshakeRTT(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	mockServer(t, func(conn *websocket.Conn) {
+		time.Sleep(delay)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success"}`))
+		time.Sleep(delay)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","ready":1}`))
+		conn.WriteMessage(websocket.BinaryMessage, pcm(320))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+		drain(conn)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	m := s.DetailedMetrics()
+	if m.HandshakeRTT < delay || m.HandshakeRTT > delay+time.Second {
+		t.Errorf("HandshakeRTT = %v, want about the %v injected", m.HandshakeRTT, delay)
+	}
+	if m.TimeToReady < delay {
+		t.Errorf("TimeToReady = %v, want at least the %v injected", m.TimeToReady, delay)
+	}
+	if m.StartedAt.IsZero() || m.HandshakeAt.Before(m.StartedAt) || m.EndedAt.Before(m.HandshakeAt) {
+		t.Errorf("timeline StartedAt %v, HandshakeAt %v, EndedAt %v out of order", m.StartedAt, m.HandshakeAt, m.EndedAt)
+	}
+}
+
+func TestStatsGrowDuringSynthesis(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	var last Stats
+	for i := 1; i <= 3; i++ {
+		conn.binary(pcm(320))
+		conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"a","BeginTime":0,"EndTime":10}]}}`)
+		waitFor(t, "frames", func() bool { return s.Stats().TextFrames == int64(i) })
+		stats := s.Stats()
+		if stats.AudioFrames != int64(i) {
+			t.Errorf("AudioFrames = %d after %d audio frames", stats.AudioFrames, i)
+		}
+		if stats.BytesReceived <= last.BytesReceived || stats.AudioFrames <= last.AudioFrames {
+			t.Errorf("Stats() = %+v after %+v, want them growing", stats, last)
+		}
+		last = stats
+	}
+	if last.Errors != 0 {
+		t.Errorf("Errors = %d, want none", last.Errors)
+	}
+}
+
+// progressListener records the fractions passed to OnProgress
+type progressListener struct {
+	recordListener
+	mutex     sync.Mutex
+	fractions []float64
+}
+
+func (l *progressListener) OnProgress(fraction float64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.fractions = append(l.fractions, fraction)
+}
+
+func (l *progressListener) progress() []float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]float64(nil), l.fractions...)
+}
+
+func TestProgressFollowsSubtitles(t *testing.T) {
+	conn := newFakeConn()
+	l := &progressListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, l)
+	s.EnableSubtitle = true
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Send("一二三四"); err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Progress(); p != 0 {
+		t.Errorf("Progress() = %g before any subtitle, want 0", p)
+	}
+	// one frame at a time: Progress is read when the listener runs, receive may be ahead
+	for i, end := range []int{1, 2, 4} {
+		conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"x","BeginIndex":%d,"EndIndex":%d}]}}`, end-1, end)
+		waitFor(t, "progress", func() bool { return len(l.progress()) == i+1 })
+	}
+	if p := s.Progress(); p != 1 {
+		t.Errorf("Progress() = %g once the text is covered, want 1", p)
+	}
+	// a subtitle reporting no further progress calls no OnProgress
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"x","BeginIndex":0,"EndIndex":1}]}}`)
+	waitFor(t, "text frames", func() bool { return l.count("text") == 4 })
+	if got, want := l.progress(), []float64{0.25, 0.5, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OnProgress fractions = %v, want %v", got, want)
+	}
+}
+
+func TestDetailedMetricsTimeline(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, pcm(320))
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if err := s.Send("你好"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	m := s.DetailedMetrics()
+	if m.StartedAt.IsZero() || m.HandshakeAt.IsZero() || m.EndedAt.IsZero() {
+		t.Fatalf("timeline StartedAt %v, HandshakeAt %v, EndedAt %v, want all set", m.StartedAt, m.HandshakeAt, m.EndedAt)
+	}
+	if m.HandshakeAt.Before(m.StartedAt) || m.EndedAt.Before(m.HandshakeAt) {
+		t.Errorf("timeline StartedAt %v, HandshakeAt %v, EndedAt %v out of order", m.StartedAt, m.HandshakeAt, m.EndedAt)
+	}
+}
+
+func TestDetailedMetricsTimelineWithoutHandshake(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {})
+	wsHostv2 = refusedHost(t)
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.ConnectTimeout = time.Second
+	if err := s.Prepare(); err == nil {
+		t.Fatal("Prepare() error = nil, want the dial refused")
+	}
+	m := s.DetailedMetrics()
+	if m.StartedAt.IsZero() {
+		t.Error("StartedAt is zero, want the dial attempt")
+	}
+	if !m.HandshakeAt.IsZero() {
+		t.Errorf("HandshakeAt = %v, want zero without a handshake", m.HandshakeAt)
+	}
+}
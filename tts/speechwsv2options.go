@@ -0,0 +1,291 @@
+package tts
+
+import (
+	"net"
+	"time"
+)
+
+// Option configures a SpeechWsv2Synthesizer
+type Option func(*SpeechWsv2Synthesizer)
+
+// WithoutVoiceValidation accepts a VoiceType missing from VoiceTypes(), e.g. a newly released voice
+func WithoutVoiceValidation() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.skipVoiceValidation = true
+	}
+}
+
+// WithFrameCapture records every frame received after ready to path, see CapturedFrame for
+// the format. Captures can be fed back with ReplayFrames.
+func WithFrameCapture(path string) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.frameCapturePath = path
+	}
+}
+
+// WithAdaptiveTimeout scales the drain timeout with the characters sent so far, at
+// estimatedCPS characters per second, clamped to [floor, ceiling]. It overrides DrainTimeout.
+func WithAdaptiveTimeout(estimatedCPS float64, floor, ceiling time.Duration) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.adaptiveTimeout = &AdaptiveTimeout{
+			CharsPerSecond: estimatedCPS,
+			Floor:          floor,
+			Ceiling:        ceiling,
+		}
+	}
+}
+
+type presignedRequest struct {
+	signature string
+	timestamp int64
+	expired   int64
+}
+
+// WithSignature reuses a signature obtained from LastSignature instead of computing one,
+// with the Timestamp/Expired it was computed for. The signature covers every request
+// parameter, SessionId included, so it is only accepted for an identical request.
+// Validate rejects it once expired.
+func WithSignature(signature string, timestamp, expired int64) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.presigned = &presignedRequest{
+			signature: signature,
+			timestamp: timestamp,
+			expired:   expired,
+		}
+	}
+}
+
+// WithUserAgent overrides the User-Agent sent in the handshake, common.UserAgent by default
+func WithUserAgent(userAgent string) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.userAgent = userAgent
+	}
+}
+
+// WithAutoSplit makes Send split chunks longer than MaxChunkChars into several frames
+// instead of rejecting them. Pieces are cut on rune boundaries, possibly mid-sentence.
+func WithAutoSplit() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.autoSplit = true
+	}
+}
+
+// WithStrictDecoding rejects structurally invalid text frames with an error wrapping
+// ErrInvalidFrame: fields of the wrong JSON type, missing required fields and subtitles
+// ending before they begin. With disallowUnknownFields, fields unknown to
+// SpeechWsv2SynthesisResponse are rejected too, which breaks as soon as the server adds one.
+func WithStrictDecoding(disallowUnknownFields bool) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.strict = &strictDecoding{disallowUnknownFields: disallowUnknownFields}
+	}
+}
+
+// WithModelType sets ModelType, one of the ModelType constants
+func WithModelType(modelType int64) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.ModelType = modelType
+	}
+}
+
+// WithDropPolicy sets what happens to events when the listener is too slow, DropNone by default
+func WithDropPolicy(policy DropPolicy) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.dropPolicy = policy
+	}
+}
+
+// WithFallbackHosts makes Prepare dial hosts in order when tts.cloud.tencent.com can't be
+// dialed, each attempt bounded by ConnectTimeout and all of them by PrepareTimeout. Errors
+// after a successful dial do not move on to the next host. The request is signed for each
+// host, so it doesn't combine with WithSignature. See EffectiveHost.
+func WithFallbackHosts(hosts []string) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.fallbackHosts = hosts
+	}
+}
+
+// WithoutRateValidation accepts a SampleRate missing from the SampleRates of the VoiceType
+func WithoutRateValidation() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.skipRateValidation = true
+	}
+}
+
+// WithFlushOnPunctuation makes Send write each sentence of a chunk as its own frame, cut
+// after 。！？；!?; or a newline, so the server can start synthesizing the first sentence
+// before the rest arrives. The protocol has no flush marker: how early synthesis starts
+// remains up to the server. Sentences are not joined across Send calls, the text after the
+// last mark of a chunk is sent as is.
+func WithFlushOnPunctuation() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.flushOnPunctuation = true
+	}
+}
+
+// WithLexicon sends a pronunciation dictionary applying to the whole session, mapping words
+// to pinyin with tone numbers ("zhong4 chong2") or to IPA between slashes ("/təˈmɑːtəʊ/").
+// Validate checks the entries.
+//
+// It is experimental: the service documents no lexicon parameter, the dictionary travels
+// as a Lexicon request parameter holding the base64url encoded JSON object of entries, a
+// format of this SDK which the server may ignore. Where a deployment expects another name
+// or format, send it through ExtParam, whose entries take precedence, or WithQueryMutator.
+func WithLexicon(entries map[string]string) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.lexicon = entries
+	}
+}
+
+// WithQueryMutator lets mutate edit the request parameters once they are all set, ExtParam
+// included, before they are sorted and signed, e.g. to try a parameter not modeled yet.
+// It is experimental and unsupported: the server may reject what mutate produces. mutate is
+// called more than once per Prepare and must make the same changes each time; values are
+// not escaped for the URL, except Text which is already escaped on some of the calls.
+func WithQueryMutator(mutate func(query map[string]string)) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.queryMutator = mutate
+	}
+}
+
+// WithJSONDebug makes the Debug output a JSON object per line, with the keys event ("sign",
+// "dial", "frame" or "close_error"), session_id, request_id, bytes, ts (RFC 3339) and
+// message, the text DebugFunc gets otherwise.
+func WithJSONDebug() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.jsonDebug = true
+	}
+}
+
+// WithoutSubtitleAccumulation stops merging the subtitles received into a list kept for the
+// whole session, which grows with the text on long sessions. OnTextResult still gets the
+// subtitles of each frame, but Subtitles and StopAndCollect return none and
+// OnSubtitleAppended is never called.
+func WithoutSubtitleAccumulation() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.noSubtitleMerge = true
+	}
+}
+
+// WithCollect keeps a copy of the audio received, for StopAndCollect to return it. The copy
+// holds all the audio of the session, so leave it off when the audio is streamed to the
+// listener or an AudioWriter.
+func WithCollect() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.collectAudio = true
+	}
+}
+
+// WithDoneChannel closes the session as Close does when done is closed, done being shared
+// by all the sessions of an application to stop them on shutdown. Wait then returns
+// ErrDone. A session started after done was closed stops right away.
+func WithDoneChannel(done <-chan struct{}) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.done = done
+	}
+}
+
+// WithRequireFinal makes Wait return ErrIncomplete when the session ended without the
+// final frame, unless it was ended by Close, Abort or another client side stop, so a
+// truncated synthesis isn't mistaken for a complete one. See WasComplete.
+func WithRequireFinal() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.requireFinal = true
+	}
+}
+
+// WithEmotion sets EmotionCategory and EmotionIntensity, Prepare fails when the emotion
+// doesn't pass Emotion.Validate or the VoiceType doesn't support it, unless
+// WithoutVoiceValidation is set
+func WithEmotion(e Emotion) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.EmotionCategory = e.Category
+		synthesizer.EmotionIntensity = e.Intensity
+	}
+}
+
+// WithCircuitBreaker makes Prepare go through b, which is meant to be shared by the
+// synthesizers using the same endpoint
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.breaker = b
+	}
+}
+
+// WithEventQueue replaces the fixed queue of 10 events between the reading of frames and
+// the listener by one growing with the backlog up to limitBytes, so a listener slow for a
+// while, e.g. on a burst of frames, doesn't stall the reading. The queue holds at most
+// limitBytes of audio plus about 256 bytes per event, a single larger frame excepted, and
+// shrinks as the listener catches up. Once it is full the DropPolicy applies as usual.
+func WithEventQueue(limitBytes int) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.queueLimit = limitBytes
+	}
+}
+
+// WithMetricsRecorder reports the start and outcome of every session to r
+func WithMetricsRecorder(r MetricsRecorder) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.recorder = r
+	}
+}
+
+// WithTracer covers every session with a span of t, from Prepare to the end of the
+// session, with the events start, first_audio and end and, once it ends, the attributes
+// session_id, request_id, voice_type, codec, bytes (audio received) and error
+func WithTracer(t Tracer) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.tracer = t
+	}
+}
+
+// WithSignatureRetry makes Prepare dial once more, with a signature computed anew, when the
+// server rejected the signature (code 4002) or the ready frame didn't come within
+// PrepareTimeout. The signature covers Timestamp and Expired: a request signed by a host
+// whose clock was skewed or stepped by NTP meanwhile, or delayed on its way, passes on a
+// second try stamped with the current time. Each attempt gets the full PrepareTimeout.
+// An injected signature (WithSignature) is never retried.
+func WithSignatureRetry() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.signatureRetry = true
+	}
+}
+
+// WithBackoff sets the delays between the attempts of PrepareWithRetry, an
+// ExponentialBackoff of 500ms up to 10s by default. A stateful b must not be shared
+// between synthesizers retrying concurrently.
+func WithBackoff(b Backoff) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.backoff = b
+	}
+}
+
+// WithInterleavedOrder guarantees OnTextResult is called before the audio it describes:
+// an audio frame is held back until a text frame reported a subtitle ending after the
+// frame starts playing, the server sending them in either order. It needs EnableSubtitle
+// and Codec pcm, it has no effect otherwise, and delays the audio up to the next text
+// frame; the audio held is released before OnSynthesisEnd and OnSynthesisFail.
+func WithInterleavedOrder() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.interleaved = true
+	}
+}
+
+// WithNetDialer opens the TCP connections with d, e.g. to bind them to a source address
+// with LocalAddr on a multi-homed host or to tune KeepAlive. With ProxyURL, d connects to
+// the proxy. Its Timeout and Deadline apply in addition to ConnectTimeout and
+// PrepareTimeout, the first to expire wins.
+func WithNetDialer(d *net.Dialer) Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.netDialer = d
+	}
+}
+
+// WithBinaryHeaders parses the header some protocol variants put ahead of the audio of
+// binary frames: the 4 bytes big-endian length of a JSON object, then the object. The
+// header is removed from the audio and passed to a SpeechWsv2AudioMetaListener; a frame
+// not starting with a valid header is taken as raw audio.
+func WithBinaryHeaders() Option {
+	return func(synthesizer *SpeechWsv2Synthesizer) {
+		synthesizer.binaryHeaders = true
+	}
+}
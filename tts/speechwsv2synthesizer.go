@@ -2,18 +2,24 @@ package tts
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
-	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"runtime/debug"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 
@@ -54,14 +60,62 @@ type Synthesisv2Subtitle struct {
 	EndIndex   int
 }
 
+// UnmarshalJSON accepts BeginTime/EndTime as integers, floats or strings,
+// fractional milliseconds are rounded down.
+func (s *Synthesisv2Subtitle) UnmarshalJSON(data []byte) error {
+	type subtitle Synthesisv2Subtitle
+	aux := struct {
+		*subtitle
+		BeginTime json.RawMessage
+		EndTime   json.RawMessage
+	}{subtitle: (*subtitle)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var err error
+	if s.BeginTime, err = parseMilliseconds(aux.BeginTime); err != nil {
+		return fmt.Errorf("BeginTime: %s", err.Error())
+	}
+	if s.EndTime, err = parseMilliseconds(aux.EndTime); err != nil {
+		return fmt.Errorf("EndTime: %s", err.Error())
+	}
+	return nil
+}
+
+func parseMilliseconds(raw json.RawMessage) (int64, error) {
+	str := strings.TrimSpace(string(raw))
+	if len(str) > 0 && str[0] == '"' {
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return 0, err
+		}
+		str = strings.TrimSpace(str)
+	}
+	if str == "" || str == "null" {
+		return 0, nil
+	}
+	if v, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid milliseconds %q", str)
+	}
+	return int64(math.Floor(f)), nil
+}
+
 // SpeechWsv2Synthesizer is the entry for TTS websocket service
 type SpeechWsv2Synthesizer struct {
-	Credential       *common.Credential
-	action           string  `json:"Action"`
-	AppID            int64   `json:"AppId"`
-	Timestamp        int64   `json:"Timestamp"`
-	Expired          int64   `json:"Expired"`
-	SessionId        string  `json:"SessionId"`
+	Credential *common.Credential
+	// CredentialProvider, when set, is asked for a fresh Credential on every Prepare
+	CredentialProvider common.CredentialProvider
+
+	action    string
+	AppID     int64  `json:"AppId"`
+	Timestamp int64  `json:"Timestamp"`
+	Expired   int64  `json:"Expired"`
+	SessionId string `json:"SessionId"`
+	// Text travels in the request URL, at most maxQueryTextBytesv2 bytes once escaped
+	// (about 450 Chinese characters), longer text must be streamed with Send
 	Text             string  `json:"Text"`
 	ModelType        int64   `json:"ModelType"`
 	VoiceType        int64   `json:"VoiceType"`
@@ -74,17 +128,117 @@ type SpeechWsv2Synthesizer struct {
 	EmotionIntensity int64   `json:"EmotionIntensity"`
 	SegmentRate      int64   `json:"SegmentRate"`
 	ExtParam         map[string]string
+	// SignatureAlgorithm selects the request signing hash, HMAC-SHA1 by default
+	SignatureAlgorithm SignatureAlgorithm
+	// TextMode controls escaping of the text passed to Send, see TextModePlain
+	TextMode TextMode
+	// MaxChunkChars caps the runes of the text passed to a single Send, 2000 by default.
+	// Longer chunks are rejected with ErrChunkTooLong, or split with WithAutoSplit.
+	// Zero disables the check.
+	MaxChunkChars int
 
-	ProxyURL    string
-	mutex       sync.Mutex
-	receiveEnd  chan int
-	eventChan   chan speechWsSynthesisEventv2
-	eventEnd    chan int
-	listener    SpeechWsv2SynthesisListener
-	status      int
-	statusMutex sync.Mutex
-	conn        *websocket.Conn //for websocet connection
-	started     bool
+	// AudioWriter receives the synthesized audio, passed through Transcoder when Codec is pcm
+	AudioWriter io.Writer
+	Transcoder  Transcoder
+
+	ProxyURL string
+	// ConnectTimeout bounds the dial and websocket upgrade, zero means no timeout
+	ConnectTimeout time.Duration
+	// PrepareTimeout bounds the whole Prepare (dial, handshake response and ready frame),
+	// zero means no timeout. It takes precedence over a longer ConnectTimeout.
+	PrepareTimeout time.Duration
+	// DrainTimeout bounds the wait for the final frame after Complete, zero means no timeout.
+	// When it expires the session is closed and Wait returns ErrDrainTimeout.
+	DrainTimeout time.Duration
+	// IdleTimeout fails the session with ErrIdleTimeout when no frame arrives for that long
+	// once started, detecting a server gone silent on a half-open connection. Heartbeat frames
+	// count as frames, keep it above the server heartbeat interval. Zero means no timeout.
+	// The time spent in Pause is not counted.
+	IdleTimeout time.Duration
+	// WriteTimeout bounds each frame written by Send and Complete, zero (the default) means
+	// no timeout. On expiry the connection is closed and the write returns ErrWriteTimeout.
+	WriteTimeout time.Duration
+	// MaxSessionDuration caps a session, counted from the end of Prepare: when it expires the
+	// session is closed as Close does and Wait returns ErrSessionDuration. It runs alongside
+	// DrainTimeout, the first to expire ends the session and sets the error of Wait. Zero
+	// means no limit.
+	MaxSessionDuration time.Duration
+	// MaxAudioBytes caps the audio of a session, e.g. for size limited messages: the frame
+	// reaching it is cut at the limit, then the session is closed as Close does, the audio
+	// writer flushed, and Wait returns ErrAudioLimitReached. Keep it a multiple of 2 with pcm.
+	// Zero means no limit.
+	MaxAudioBytes int64
+
+	mutex         sync.Mutex
+	receiveEnd    chan int
+	eventChan     chan speechWsSynthesisEventv2
+	queue         *eventQueue // fills eventChan with WithEventQueue
+	eventEnd      chan int
+	listener      SpeechWsv2SynthesisListener
+	status        int
+	statusMutex   sync.Mutex
+	conn          wsConn //for websocet connection
+	writeMutex    sync.Mutex
+	started       bool
+	completeMutex sync.Mutex
+	completed     bool // ACTION_COMPLETE written, guarded by completeMutex
+	audioErr      error
+	terminated    bool            // session ended on the client's initiative, guarded by statusMutex
+	termErr       error           // reason returned by Wait, guarded by statusMutex
+	shutdownCh    chan struct{}   // closed by shutdown
+	drainTimer    *time.Timer     // guarded by statusMutex
+	sessionTimer  *time.Timer     // MaxSessionDuration, guarded by statusMutex
+	signature     string          // last computed or injected signature, guarded by statusMutex
+	signedAt      time.Time       // guarded by statusMutex
+	host          string          // host being signed for or dialed, guarded by mutex
+	effectiveHost string          // guarded by statusMutex
+	requestId     string          // from the handshake response, guarded by statusMutex
+	speed         *float64        // set by SetSpeed, guarded by statusMutex
+	volume        *float64        // set by SetVolume, guarded by statusMutex
+	unacked       map[string]bool // message_id of the chunks sent, guarded by statusMutex
+	acked         []string        // guarded by statusMutex
+	paused        chan struct{}   // non nil while paused, closed by Resume, guarded by statusMutex
+	closeOnce     sync.Once
+	endOnce       sync.Once
+	audioBytes    int64   // audio dispatched so far, owned by eventDispatch
+	lastProgress  float64 // last value passed to OnProgress, owned by eventDispatch
+	closeErr      error   // error of the connection close, set by closeOnce
+	failure       error   // first failure dispatched, owned by eventDispatch
+	span          Span    // set before the session goroutines start
+	aborted       int32
+
+	skipVoiceValidation bool
+	frameCapturePath    string
+	adaptiveTimeout     *AdaptiveTimeout
+	presigned           *presignedRequest
+	userAgent           string
+	autoSplit           bool
+	strict              *strictDecoding
+	dropPolicy          DropPolicy
+	fallbackHosts       []string
+	skipRateValidation  bool
+	flushOnPunctuation  bool
+	lexicon             map[string]string
+	queryMutator        func(map[string]string)
+	jsonDebug           bool
+	noSubtitleMerge     bool
+	collectAudio        bool
+	done                <-chan struct{}
+	requireFinal        bool
+	breaker             *CircuitBreaker
+	signatureRetry      bool
+	queueLimit          int
+	recorder            MetricsRecorder
+	tracer              Tracer
+	backoff             Backoff
+	interleaved         bool
+	netDialer           *net.Dialer
+	binaryHeaders       bool
+
+	metricsMutex sync.Mutex
+	timing       wsv2Timing
+	counters     *wsv2Counters
+	collector    *wsv2Collector
 
 	Debug     bool //是否debug
 	DebugFunc func(message string)
@@ -99,17 +253,104 @@ type SpeechWsv2SynthesisListener interface {
 	OnSynthesisFail(*SpeechWsv2SynthesisResponse, error)
 }
 
+// NoopListener implements SpeechWsv2SynthesisListener with methods doing nothing. Embed it
+// to override only the callbacks of interest, e.g. when the audio goes to an AudioWriter:
+//
+//	type failureListener struct {
+//		tts.NoopListener
+//	}
+//
+//	func (l *failureListener) OnSynthesisFail(r *tts.SpeechWsv2SynthesisResponse, err error) {
+//		log.Println(err)
+//	}
+type NoopListener struct{}
+
+func (NoopListener) OnSynthesisStart(*SpeechWsv2SynthesisResponse)       {}
+func (NoopListener) OnSynthesisEnd(*SpeechWsv2SynthesisResponse)         {}
+func (NoopListener) OnAudioResult(data []byte)                           {}
+func (NoopListener) OnTextResult(*SpeechWsv2SynthesisResponse)           {}
+func (NoopListener) OnSynthesisFail(*SpeechWsv2SynthesisResponse, error) {}
+
+// SpeechWsv2SubtitleListener can be implemented in addition to SpeechWsv2SynthesisListener to
+// receive each subtitle exactly once, in order, as soon as the server won't revise it anymore
+// (a later subtitle arrived or the synthesis ended). OnTextResult is still called as before.
+type SpeechWsv2SubtitleListener interface {
+	OnSubtitleAppended(sub Synthesisv2Subtitle)
+}
+
+// SpeechWsv2CheckedListener can be implemented in addition to SpeechWsv2SynthesisListener by
+// listeners that need to stop the session, e.g. when the client they relay to disconnected.
+// Its methods are then called instead of OnAudioResult and OnTextResult. A non nil error
+// ends the session as Close does: reading stops, the frames already received are still
+// dispatched and the AudioWriter flushed, then Wait returns the error. OnSynthesisFail is
+// not called.
+type SpeechWsv2CheckedListener interface {
+	OnAudioResultChecked(data []byte) error
+	OnTextResultChecked(*SpeechWsv2SynthesisResponse) error
+}
+
+// SpeechWsv2TimedAudioListener can be implemented in addition to SpeechWsv2SynthesisListener
+// to get the playout offset of each audio frame from the start of the synthesis, e.g. for
+// lip-sync. It is computed from the bytes received, so only when Codec is pcm; it is called
+// after OnAudioResult with the same data.
+type SpeechWsv2TimedAudioListener interface {
+	OnAudioResultAt(offset time.Duration, data []byte)
+}
+
+// SpeechWsv2ProgressListener can be implemented in addition to SpeechWsv2SynthesisListener
+// to follow Progress, e.g. for a progress bar. OnProgress is called after OnTextResult when
+// the progress moved, and with 1 before OnSynthesisEnd. It needs EnableSubtitle.
+type SpeechWsv2ProgressListener interface {
+	OnProgress(fraction float64)
+}
+
+// SpeechWsv2AckListener can be implemented in addition to SpeechWsv2SynthesisListener to be
+// told when a frame echoes the message_id of a chunk written by Send, see AckedMessageIDs.
+// OnAck is called before the callbacks of that frame.
+type SpeechWsv2AckListener interface {
+	OnAck(messageID string)
+}
+
+// SpeechWsv2UnknownFrameListener can be implemented in addition to SpeechWsv2SynthesisListener
+// to see the frames that are neither text nor binary messages, which the server is not
+// expected to send. Such frames are skipped, logged with Debug, and do not end the session.
+type SpeechWsv2UnknownFrameListener interface {
+	OnUnknownFrame(messageType int, data []byte)
+}
+
+// SpeechWsv2ReadyListener can be implemented in addition to SpeechWsv2SynthesisListener to be
+// notified when the server re-emits ready after the session started, e.g. after a resume.
+// Such frames are never delivered to OnTextResult.
+type SpeechWsv2ReadyListener interface {
+	OnReady(*SpeechWsv2SynthesisResponse)
+}
+
+const (
+	defaultWsVoiceTypev2   = 0
+	defaultWsSampleRatev2  = 16000
+	defaultWsCodecv2       = "pcm"
+	defaultMaxChunkCharsv2 = 2000
+	defaultWsActionv2      = "TextToStreamAudioWSv2"
+	wsConnectTimeoutv2     = 2000
+	wsReadHeaderTimeoutv2  = 2000
+	maxWsMessageSizev2     = 10240
+	maxQueryTextBytesv2    = 4096
+	maxPartialFramev2      = 64 * 1024
+	wsPathv2               = "/stream_wsv2"
+)
+
+// the endpoint of the service, tests point them at a local server
+var (
+	wsProtocolv2 = "wss"
+	wsHostv2     = "tts.cloud.tencent.com"
+)
+
+// ModelType values, assign them to ModelType or pass them to WithModelType
 const (
-	defaultWsVoiceTypev2  = 0
-	defaultWsSampleRatev2 = 16000
-	defaultWsCodecv2      = "pcm"
-	defaultWsActionv2     = "TextToStreamAudioWSv2"
-	wsConnectTimeoutv2    = 2000
-	wsReadHeaderTimeoutv2 = 2000
-	maxWsMessageSizev2    = 10240
-	wsProtocolv2          = "wss"
-	wsHostv2              = "tts.cloud.tencent.com"
-	wsPathv2              = "/stream_wsv2"
+	// ModelTypeUnset lets the server pick its default model
+	ModelTypeUnset int64 = 0
+	// ModelTypeDefault is the standard synthesis model
+	ModelTypeDefault int64 = 1
 )
 
 const (
@@ -118,20 +359,31 @@ const (
 	eventTypeWsAudioResultv2
 	eventTypeWsTextResultv2
 	eventTypeWsFailv2
+	eventTypeWsReadyv2
+	eventTypeWsSubtitleAppendedv2
+	eventTypeWsAckv2
+	eventTypeWsUnknownFramev2
 )
 
 type eventWsTypev2 int
 
+// Now stamps Timestamp/Expired of the signed requests, tests may replace it to get
+// reproducible signatures
+var Now = time.Now
+
 type speechWsSynthesisEventv2 struct {
-	t   eventWsTypev2
-	r   *SpeechWsv2SynthesisResponse
-	d   []byte
-	err error
+	t    eventWsTypev2
+	r    *SpeechWsv2SynthesisResponse
+	d    []byte
+	subs []Synthesisv2Subtitle
+	op   int             // websocket message type of eventTypeWsUnknownFramev2
+	meta *AudioFrameMeta // header of the audio with WithBinaryHeaders
+	err  error
 }
 
 // NewSpeechWsv2Synthesizer creates instance of SpeechWsv2Synthesizer
-func NewSpeechWsv2Synthesizer(appID int64, credential *common.Credential, listener SpeechWsv2SynthesisListener) *SpeechWsv2Synthesizer {
-	return &SpeechWsv2Synthesizer{
+func NewSpeechWsv2Synthesizer(appID int64, credential *common.Credential, listener SpeechWsv2SynthesisListener, opts ...Option) *SpeechWsv2Synthesizer {
+	synthesizer := &SpeechWsv2Synthesizer{
 		AppID:      appID,
 		Credential: credential,
 		action:     defaultWsActionv2,
@@ -139,207 +391,1188 @@ func NewSpeechWsv2Synthesizer(appID int64, credential *common.Credential, listen
 		SampleRate: defaultWsSampleRatev2,
 		Codec:      defaultWsCodecv2,
 		listener:   listener,
-		status:     0,
-		receiveEnd: make(chan int),
-		eventChan:  make(chan speechWsSynthesisEventv2, 10),
-		eventEnd:   make(chan int),
+
+		MaxChunkChars: defaultMaxChunkCharsv2,
+	}
+	synthesizer.resetSession()
+	for _, opt := range opts {
+		opt(synthesizer)
+	}
+	return synthesizer
+}
+
+// Reset prepares a finished synthesizer for a new session, keeping its configuration.
+// The SessionId is cleared so Prepare generates a new one.
+func (synthesizer *SpeechWsv2Synthesizer) Reset() error {
+	synthesizer.mutex.Lock()
+	defer synthesizer.mutex.Unlock()
+	if synthesizer.started {
+		select {
+		case <-synthesizer.receiveEnd:
+		default:
+			return fmt.Errorf("synthesizer is running")
+		}
+		<-synthesizer.eventEnd
+	}
+	synthesizer.SessionId = ""
+	synthesizer.resetSession()
+	return nil
+}
+
+// resetSession initializes the per session state
+func (synthesizer *SpeechWsv2Synthesizer) resetSession() {
+	synthesizer.status = 0
+	synthesizer.receiveEnd = make(chan int)
+	synthesizer.eventChan = make(chan speechWsSynthesisEventv2, 10)
+	synthesizer.queue = nil
+	synthesizer.eventEnd = make(chan int)
+	synthesizer.conn = nil
+	synthesizer.started = false
+	synthesizer.completed = false
+	synthesizer.audioErr = nil
+	synthesizer.terminated = false
+	synthesizer.termErr = nil
+	synthesizer.shutdownCh = make(chan struct{})
+	synthesizer.drainTimer = nil
+	synthesizer.sessionTimer = nil
+	synthesizer.signature = ""
+	synthesizer.signedAt = time.Time{}
+	synthesizer.host = ""
+	synthesizer.effectiveHost = ""
+	synthesizer.requestId = ""
+	synthesizer.speed = nil
+	synthesizer.volume = nil
+	synthesizer.unacked = map[string]bool{}
+	synthesizer.acked = nil
+	synthesizer.paused = nil
+	synthesizer.closeOnce = sync.Once{}
+	synthesizer.endOnce = sync.Once{}
+	synthesizer.audioBytes = 0
+	synthesizer.lastProgress = 0
+	synthesizer.closeErr = nil
+	synthesizer.failure = nil
+	synthesizer.span = nil
+	synthesizer.aborted = 0
+	synthesizer.timing = wsv2Timing{}
+	synthesizer.counters = &wsv2Counters{}
+	synthesizer.collector = &wsv2Collector{}
+}
+
+// NewSpeechWsv2SynthesizerWithProvider creates instance of SpeechWsv2Synthesizer signing with
+// credentials fetched from provider
+func NewSpeechWsv2SynthesizerWithProvider(appID int64, provider common.CredentialProvider, listener SpeechWsv2SynthesisListener, opts ...Option) *SpeechWsv2Synthesizer {
+	synthesizer := NewSpeechWsv2Synthesizer(appID, nil, listener, opts...)
+	synthesizer.CredentialProvider = provider
+	return synthesizer
+}
+
+// Synthesis Start connects to server and start a synthesizer session. A failed Prepare
+// closes the connection it opened and starts no goroutine, there is nothing to Wait for.
+func (synthesizer *SpeechWsv2Synthesizer) Prepare() (err error) {
+	synthesizer.mutex.Lock()
+	defer synthesizer.mutex.Unlock()
+
+	if synthesizer.started {
+		return fmt.Errorf("synthesizer is already started")
 	}
+	synthesizer.startSpan()
+	defer func() {
+		if err != nil {
+			synthesizer.endSpan(err)
+		}
+	}()
+	if err := synthesizer.prepareRequest(); err != nil {
+		return err
+	}
+	conn, msg, err := synthesizer.connect(context.Background())
+	if err != nil {
+		synthesizer.recordPrepareFailure(err)
+		return err
+	}
+	return synthesizer.startConn(conn, msg)
 }
 
-// Synthesis Start connects to server and start a synthesizer session
-func (synthesizer *SpeechWsv2Synthesizer) Prepare() error {
+// VerifyCredential checks the credential by connecting and waiting for the ready frame as
+// Prepare does, then closes the connection without sending any text, so nothing is
+// synthesized nor billed. A rejected credential returns an error matching ErrAuthFailed.
+// ctx bounds the whole check. The synthesizer is left ready for Prepare.
+func (synthesizer *SpeechWsv2Synthesizer) VerifyCredential(ctx context.Context) error {
 	synthesizer.mutex.Lock()
 	defer synthesizer.mutex.Unlock()
 
 	if synthesizer.started {
 		return fmt.Errorf("synthesizer is already started")
 	}
+	sessionId := synthesizer.SessionId
+	defer func() {
+		// a session id generated for the check is not reused by Prepare
+		synthesizer.SessionId = sessionId
+		synthesizer.resetSession()
+	}()
+	if err := synthesizer.prepareRequest(); err != nil {
+		return err
+	}
+	conn, _, err := synthesizer.connect(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return conn.Close()
+}
+
+// startConn starts the session on a connection returned by connect
+func (synthesizer *SpeechWsv2Synthesizer) startConn(conn *websocket.Conn, msg *SpeechWsv2SynthesisResponse) error {
+	if synthesizer.frameCapturePath != "" {
+		capture, err := newCaptureConn(conn, synthesizer.frameCapturePath)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("session_id: %s, frame capture error: %s", synthesizer.SessionId, err.Error())
+		}
+		synthesizer.start(capture, msg)
+		return nil
+	}
+	synthesizer.start(conn, msg)
+	return nil
+}
+
+// ReplayFrames starts a session fed with the frames of a capture file written by
+// WithFrameCapture instead of connecting to the server. Writes are discarded; use Wait
+// as with Prepare.
+func (synthesizer *SpeechWsv2Synthesizer) ReplayFrames(path string) error {
+	synthesizer.mutex.Lock()
+	defer synthesizer.mutex.Unlock()
+
+	if synthesizer.started {
+		return fmt.Errorf("synthesizer is already started")
+	}
+	frames, err := ReadFrameCapture(path)
+	if err != nil {
+		return err
+	}
+	if synthesizer.SessionId == "" {
+		synthesizer.SessionId = uuid.New().String()
+	}
+	synthesizer.start(newReplayConn(frames), &SpeechWsv2SynthesisResponse{SessionId: synthesizer.SessionId})
+	return nil
+}
+
+// ConfigSnapshot returns the parameters of the request as Prepare sends them, to log at
+// the start of a session, e.g. for support tickets: voice, codec, sample rate, speed,
+// volume, emotion, model, ExtParam and so on, keyed by their request name, plus Host. The
+// SecretId is masked, Text and Token are left out. Timestamp and Expired are those of the
+// last signature, zero before Prepare.
+func (synthesizer *SpeechWsv2Synthesizer) ConfigSnapshot() map[string]string {
+	snapshot := synthesizer.queryParams(false)
+	delete(snapshot, "Text")
+	delete(snapshot, "Token")
+	if secretId, ok := snapshot["SecretId"]; ok {
+		snapshot["SecretId"] = maskSecret(secretId)
+	}
+	synthesizer.statusMutex.Lock()
+	snapshot["Host"] = synthesizer.effectiveHost
+	synthesizer.statusMutex.Unlock()
+	if snapshot["Host"] == "" {
+		snapshot["Host"] = wsHostv2
+	}
+	return snapshot
+}
+
+// maskSecret keeps the last 4 characters of a secret, enough to tell keys apart
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+// BuildSignedURL returns the signed wss URL Prepare would dial, without connecting. The
+// SecretKey only appears through the computed Signature.
+func (synthesizer *SpeechWsv2Synthesizer) BuildSignedURL() (string, error) {
+	synthesizer.mutex.Lock()
+	defer synthesizer.mutex.Unlock()
+	if err := synthesizer.prepareRequest(); err != nil {
+		return "", err
+	}
+	return synthesizer.signedURL(), nil
+}
+
+// prepareRequest resolves the credential, validates the parameters and assigns the SessionId
+func (synthesizer *SpeechWsv2Synthesizer) prepareRequest() error {
+	provider := synthesizer.CredentialProvider
+	if provider == nil {
+		provider = common.NewStaticCredentialProvider(synthesizer.Credential)
+	}
+	credential, err := provider.GetCredential()
+	if err != nil {
+		return fmt.Errorf("get credential error: %s", err.Error())
+	}
+	synthesizer.Credential = credential
+	if err := synthesizer.Validate(); err != nil {
+		return err
+	}
 	if synthesizer.SessionId == "" {
 		SessionId := uuid.New().String()
 		synthesizer.SessionId = SessionId
 	}
-	var timestamp = time.Now().Unix()
-	synthesizer.Timestamp = timestamp
-	synthesizer.Expired = timestamp + 24*60*60
-	serverURL := synthesizer.buildURL(false)
-	signature := synthesizer.genWsSignature(serverURL, synthesizer.Credential.SecretKey)
-	if synthesizer.Debug && synthesizer.DebugFunc != nil {
-		logMsg := fmt.Sprintf("serverURL:%s , signature:%s", serverURL, signature)
-		synthesizer.DebugFunc(logMsg)
+	return nil
+}
+
+// signedURL stamps Timestamp/Expired and returns the signed URL to dial
+func (synthesizer *SpeechWsv2Synthesizer) signedURL() string {
+	var signature string
+	var serverURL string
+	if p := synthesizer.presigned; p != nil {
+		synthesizer.Timestamp = p.timestamp
+		synthesizer.Expired = p.expired
+		serverURL = synthesizer.buildURL(false)
+		signature = p.signature
+	} else {
+		var timestamp = Now().Unix()
+		synthesizer.Timestamp = timestamp
+		synthesizer.Expired = timestamp + 24*60*60
+		serverURL = synthesizer.buildURL(false)
+		signature = synthesizer.genWsSignature(serverURL, synthesizer.Credential.SecretKey)
+	}
+	synthesizer.statusMutex.Lock()
+	synthesizer.signature = signature
+	synthesizer.signedAt = time.Unix(synthesizer.Timestamp, 0)
+	synthesizer.statusMutex.Unlock()
+	synthesizer.debug("sign", 0, fmt.Sprintf("serverURL:%s , signature:%s", serverURL, signature))
+	serverURL = synthesizer.buildURL(true)
+	return fmt.Sprintf("%s://%s&Signature=%s", wsProtocolv2, serverURL, url.QueryEscape(signature))
+}
+
+// LastSignature returns the signature of the last Prepare or BuildSignedURL
+func (synthesizer *SpeechWsv2Synthesizer) LastSignature() string {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.signature
+}
+
+// SessionInfo identifies a session, see Session
+type SessionInfo struct {
+	SessionId string // generated by the client
+	RequestId string // assigned by the server in the handshake response
+}
+
+// Session returns the identifiers of the current session, it may be called from the
+// listener callbacks, e.g. by a listener shared between synthesizers to tag its logs
+func (synthesizer *SpeechWsv2Synthesizer) Session() *SessionInfo {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return &SessionInfo{SessionId: synthesizer.SessionId, RequestId: synthesizer.requestId}
+}
+
+// EffectiveHost returns the host the last Prepare connected to, empty before it connected
+func (synthesizer *SpeechWsv2Synthesizer) EffectiveHost() string {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.effectiveHost
+}
+
+// LastSignedAt returns the Timestamp the last signature was computed for
+func (synthesizer *SpeechWsv2Synthesizer) LastSignedAt() time.Time {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.signedAt
+}
+
+// Validate checks the request parameters before connecting, it is called by Prepare
+func (synthesizer *SpeechWsv2Synthesizer) Validate() error {
+	switch credential := synthesizer.Credential; {
+	case credential == nil:
+		return fmt.Errorf("credential is nil")
+	case credential.SecretId == "":
+		return fmt.Errorf("credential SecretId is empty")
+	case credential.SecretKey == "" && synthesizer.presigned == nil:
+		// an injected signature needs no SecretKey
+		return fmt.Errorf("credential SecretKey is empty")
+	}
+	if p := synthesizer.presigned; p != nil && Now().Unix() >= p.expired {
+		return fmt.Errorf("injected signature expired at %s", time.Unix(p.expired, 0).Format(time.RFC3339))
+	}
+	if synthesizer.ModelType != ModelTypeUnset && synthesizer.ModelType != ModelTypeDefault {
+		return fmt.Errorf("unknown ModelType %d, see the ModelType constants", synthesizer.ModelType)
+	}
+	if err := validateLexicon(synthesizer.lexicon); err != nil {
+		return err
+	}
+	if d := synthesizer.netDialer; d != nil && d.LocalAddr != nil {
+		if _, ok := d.LocalAddr.(*net.TCPAddr); !ok {
+			return fmt.Errorf("net dialer LocalAddr %s is not a TCP address", d.LocalAddr)
+		}
+	}
+	if synthesizer.EmotionCategory != "" {
+		if err := (Emotion{Category: synthesizer.EmotionCategory, Intensity: synthesizer.EmotionIntensity}).Validate(); err != nil {
+			return err
+		}
+		if err := synthesizer.checkEmotion(synthesizer.EmotionCategory); err != nil {
+			return err
+		}
+	}
+	if n := len(url.QueryEscape(synthesizer.Text)); n > maxQueryTextBytesv2 {
+		return fmt.Errorf("%w: Text is %d bytes once escaped, the request URL allows %d, send it with Send instead",
+			ErrTextTooLong, n, maxQueryTextBytesv2)
+	}
+	return synthesizer.checkVoice(synthesizer.VoiceType)
+}
+
+// checkVoice checks voiceType against the catalog and SampleRate against its rates
+func (synthesizer *SpeechWsv2Synthesizer) checkVoice(voiceType int64) error {
+	voice, known := LookupVoice(voiceType)
+	if !known && !synthesizer.skipVoiceValidation {
+		return fmt.Errorf("unknown VoiceType %d, see VoiceTypes() or use WithoutVoiceValidation", voiceType)
+	}
+	if known && !synthesizer.skipRateValidation {
+		supported := false
+		rates := make([]string, len(voice.SampleRates))
+		for i, rate := range voice.SampleRates {
+			supported = supported || rate == synthesizer.SampleRate
+			rates[i] = strconv.FormatInt(rate, 10)
+		}
+		if !supported {
+			return fmt.Errorf("SampleRate %d not supported by VoiceType %d, allowed: %s, or use WithoutRateValidation",
+				synthesizer.SampleRate, voiceType, strings.Join(rates, ", "))
+		}
+	}
+	return nil
+}
+
+// connect runs dial unless the CircuitBreaker is open, and reports the outcome to it.
+// With WithSignatureRetry a rejected signature is dialed again, signed anew.
+func (synthesizer *SpeechWsv2Synthesizer) connect(ctx context.Context) (*websocket.Conn, *SpeechWsv2SynthesisResponse, error) {
+	breaker := synthesizer.breaker
+	if breaker != nil && !breaker.allow() {
+		return nil, nil, &SynthesisError{SessionId: synthesizer.SessionId, Err: ErrCircuitOpen}
+	}
+	conn, msg, err := synthesizer.dial(ctx)
+	if err != nil && synthesizer.signatureRetry && synthesizer.presigned == nil && signatureRejected(err) {
+		synthesizer.debug("sign", 0, fmt.Sprintf("retrying with a fresh signature after: %s", err.Error()))
+		conn, msg, err = synthesizer.dial(ctx)
+	}
+	if breaker != nil {
+		breaker.record(err)
+	}
+	return conn, msg, err
+}
+
+// dial dials the server and waits for the ready frame. PrepareTimeout, or the deadline of
+// ctx if earlier, bounds the whole sequence, ConnectTimeout only the dial, whichever
+// expires first wins.
+func (synthesizer *SpeechWsv2Synthesizer) dial(ctx context.Context) (*websocket.Conn, *SpeechWsv2SynthesisResponse, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: synthesizer.ConnectTimeout}
+	if synthesizer.netDialer != nil {
+		dialer.NetDialContext = synthesizer.netDialer.DialContext
 	}
-	dialer := websocket.Dialer{}
 	if len(synthesizer.ProxyURL) > 0 {
 		proxyURL, _ := url.Parse(synthesizer.ProxyURL)
 		dialer.Proxy = http.ProxyURL(proxyURL)
 	}
-	serverURL = synthesizer.buildURL(true)
 	header := http.Header(make(map[string][]string))
-	urlStr := fmt.Sprintf("%s://%s&Signature=%s", wsProtocolv2, serverURL, url.QueryEscape(signature))
-	if synthesizer.Debug && synthesizer.DebugFunc != nil {
-		logMsg := fmt.Sprintf("urlStr:%s ", urlStr)
-		synthesizer.DebugFunc(logMsg)
+	if synthesizer.userAgent != "" {
+		header.Set("User-Agent", synthesizer.userAgent)
+	} else {
+		header.Set("User-Agent", common.UserAgent)
+	}
+	synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) { t.startedAt = now })
+	deadline, _ := ctx.Deadline()
+	if synthesizer.PrepareTimeout > 0 {
+		if d := time.Now().Add(synthesizer.PrepareTimeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	fail := func(conn *websocket.Conn, err error) (*websocket.Conn, *SpeechWsv2SynthesisResponse, error) {
+		if conn != nil {
+			conn.Close()
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			err = ErrPrepareTimeout
+		}
+		return nil, nil, &SynthesisError{SessionId: synthesizer.SessionId, Err: err}
+	}
+
+	// a dial failure moves on to the next host, any later failure is final
+	var conn *websocket.Conn
+	var err error
+	for _, host := range append([]string{wsHostv2}, synthesizer.fallbackHosts...) {
+		synthesizer.host = host
+		urlStr := synthesizer.signedURL()
+		synthesizer.debug("dial", 0, fmt.Sprintf("urlStr:%s ", urlStr))
+		synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) { t.dialAt = now })
+		conn, _, err = dialer.DialContext(ctx, urlStr, header)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
 	}
-	conn, _, err := dialer.Dial(urlStr, header)
 	if err != nil {
-		return fmt.Errorf("session_id: %s, error: %s", synthesizer.SessionId, err.Error())
+		return fail(nil, err)
+	}
+	synthesizer.statusMutex.Lock()
+	synthesizer.effectiveHost = synthesizer.host
+	synthesizer.statusMutex.Unlock()
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return fail(conn, err)
 	}
 	_, data, err := conn.ReadMessage()
 	if err != nil {
-		conn.Close()
-		return fmt.Errorf("session_id: %s, error: %s", synthesizer.SessionId, err.Error())
+		return fail(conn, err)
 	}
+	synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) { t.handshakeAt = now })
 	msg := SpeechWsv2SynthesisResponse{}
 	err = json.Unmarshal(data, &msg)
 	if err != nil {
-		conn.Close()
-		return fmt.Errorf("session_id: %s, error: %s", synthesizer.SessionId, err.Error())
+		return fail(conn, err)
 	}
 	if msg.Code != 0 {
 		conn.Close()
-		return fmt.Errorf("session_id: %s, code: %d, message: %s",
-			synthesizer.SessionId, msg.Code, msg.Message)
+		return nil, nil, &SynthesisError{SessionId: synthesizer.SessionId, Code: msg.Code, Message: msg.Message}
+	}
+	if err := checkProtocolVersion(data); err != nil {
+		return fail(conn, err)
 	}
 	msg.SessionId = synthesizer.SessionId
-	synthesizer.conn = conn
 	// wait ready
 	for {
-		optCode, data, err := synthesizer.conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
-			conn.Close()
-			return err
-		}
-		if optCode == websocket.TextMessage {
-			if msg.Code != 0 {
-				conn.Close()
-				return fmt.Errorf(msg.Message)
-			}
+			return fail(conn, err)
 		}
 		msg2 := SpeechWsv2SynthesisResponse{}
-		if err2 := json.Unmarshal(data, &msg2); err2 != nil {
-			return err
+		if err := json.Unmarshal(data, &msg2); err != nil {
+			return fail(conn, err)
+		}
+		if msg2.Code != 0 {
+			conn.Close()
+			return nil, nil, &SynthesisError{SessionId: synthesizer.SessionId, Code: msg2.Code, Message: msg2.Message}
 		}
 		if msg2.Ready == 1 {
+			synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) { t.readyAt = now })
 			break
 		}
 	}
-	// send
-	go synthesizer.receive()
-	go synthesizer.eventDispatch()
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return fail(conn, err)
+	}
+	return conn, &msg, nil
+}
+
+// start runs the session goroutines on an established connection. It cannot fail: dial
+// and startConn do everything that may, closing the connection on error, so the goroutines
+// of a session are started all or none and a failed Prepare leaves none behind.
+func (synthesizer *SpeechWsv2Synthesizer) start(conn wsConn, msg *SpeechWsv2SynthesisResponse) {
+	synthesizer.conn = conn
 	synthesizer.started = true
+	synthesizer.statusMutex.Lock()
+	synthesizer.requestId = msg.RequestId
+	synthesizer.statusMutex.Unlock()
+	synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) {
+		if t.startedAt.IsZero() {
+			t.startedAt = now
+		}
+	})
 	synthesizer.setStatus(eventTypeWsStartv2)
-	synthesizer.eventChan <- speechWsSynthesisEventv2{
+	synthesizer.startSessionTimer()
+	if synthesizer.recorder != nil {
+		synthesizer.recorder.SessionStarted()
+	}
+	synthesizer.startSpan()
+	synthesizer.spanEvent("start")
+	if synthesizer.queueLimit > 0 {
+		synthesizer.queue = newEventQueue(synthesizer.queueLimit)
+		go synthesizer.queue.pump(synthesizer.eventChan)
+	}
+	// queued before receive() runs, it may close eventChan at once
+	synthesizer.emit(speechWsSynthesisEventv2{
 		t:   eventTypeWsStartv2,
-		r:   &msg,
+		r:   msg,
 		err: nil,
+	})
+	go synthesizer.receive()
+	go synthesizer.eventDispatch()
+	if synthesizer.done != nil {
+		go synthesizer.watchDone()
+	}
+}
+
+// watchDone closes the session when the channel of WithDoneChannel is closed, it returns
+// when the session ends first
+func (synthesizer *SpeechWsv2Synthesizer) watchDone() {
+	select {
+	case <-synthesizer.done:
+		synthesizer.shutdown(ErrDone)
+	case <-synthesizer.receiveEnd:
 	}
-	return nil
 }
 
+// Send writes chunk for synthesis. Chunks longer than MaxChunkChars runes are rejected,
+// or sent as several frames when WithAutoSplit is set.
 func (synthesizer *SpeechWsv2Synthesizer) Send(chunk string) error {
-	return synthesizer.conn.WriteJSON(map[string]interface{}{
+	return synthesizer.sendText(chunk, nil)
+}
+
+// SendWithEmotion writes chunk like Send, asking for the emotion category and intensity
+// (50 to 200, 100 being neutral) for this chunk only, e.g. to ramp intensity across the
+// sentences of a story. The frame carries them as emotion_category/emotion_intensity;
+// servers or voices without per-chunk emotion support ignore them and keep the
+// EmotionCategory/EmotionIntensity of the request. The category must be one of the
+// Emotion constants, and is checked against the Emotions of the VoiceType unless
+// WithoutVoiceValidation is set.
+func (synthesizer *SpeechWsv2Synthesizer) SendWithEmotion(chunk string, category string, intensity int64) error {
+	if intensity < 50 || intensity > 200 {
+		return fmt.Errorf("emotion intensity %d out of range [50, 200]", intensity)
+	}
+	if err := (Emotion{Category: category, Intensity: intensity}).Validate(); err != nil {
+		return err
+	}
+	if err := synthesizer.checkEmotion(category); err != nil {
+		return err
+	}
+	return synthesizer.sendText(chunk, map[string]interface{}{
+		"emotion_category":  category,
+		"emotion_intensity": intensity,
+	})
+}
+
+// SetSpeed changes the speed of the text sent afterwards, from -2 (0.6x) to 6 (2.5x), 0
+// being normal like Speed. Subsequent frames carry it as speed; servers without per-chunk
+// prosody support ignore it and keep Speed.
+func (synthesizer *SpeechWsv2Synthesizer) SetSpeed(speed float64) error {
+	if speed < -2 || speed > 6 {
+		return fmt.Errorf("speed %g out of range [-2, 6]", speed)
+	}
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	synthesizer.speed = &speed
+	return nil
+}
+
+// SetVolume changes the volume of the text sent afterwards, from -10 to 10, 0 being normal
+// like Volume. Subsequent frames carry it as volume, see SetSpeed for server support.
+func (synthesizer *SpeechWsv2Synthesizer) SetVolume(volume float64) error {
+	if volume < -10 || volume > 10 {
+		return fmt.Errorf("volume %g out of range [-10, 10]", volume)
+	}
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	synthesizer.volume = &volume
+	return nil
+}
+
+// SendWithVoice writes chunk like Send, read with voiceType instead of VoiceType, e.g. to
+// switch speakers in a dialogue. The frame carries it as voice_type; the public protocol
+// documents no per-chunk voice, servers without support ignore it and keep VoiceType.
+// voiceType is validated as VoiceType is, against the catalog and SampleRate.
+func (synthesizer *SpeechWsv2Synthesizer) SendWithVoice(chunk string, voiceType int64) error {
+	if err := synthesizer.checkVoice(voiceType); err != nil {
+		return err
+	}
+	return synthesizer.sendText(chunk, map[string]interface{}{
+		"voice_type": voiceType,
+	})
+}
+
+// sendText applies WithFlushOnPunctuation and MaxChunkChars and writes chunk, adding fields
+// to every frame
+func (synthesizer *SpeechWsv2Synthesizer) sendText(chunk string, fields map[string]interface{}) error {
+	if synthesizer.flushOnPunctuation {
+		for _, sentence := range splitSentences(chunk) {
+			if err := synthesizer.sendLimited(sentence, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return synthesizer.sendLimited(chunk, fields)
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) sendLimited(chunk string, fields map[string]interface{}) error {
+	max := synthesizer.MaxChunkChars
+	if max <= 0 || utf8.RuneCountInString(chunk) <= max {
+		return synthesizer.sendChunk(chunk, fields)
+	}
+	if !synthesizer.autoSplit {
+		return fmt.Errorf("session_id: %s, error: %w: %d runes, max %d",
+			synthesizer.SessionId, ErrChunkTooLong, utf8.RuneCountInString(chunk), max)
+	}
+	for _, part := range splitRunes(chunk, max) {
+		if err := synthesizer.sendChunk(part, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitRunes cuts s into pieces of at most max runes
+func splitRunes(s string, max int) []string {
+	var parts []string
+	for s != "" {
+		end, n := 0, 0
+		for end < len(s) && n < max {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+			n++
+		}
+		parts = append(parts, s[:end])
+		s = s[end:]
+	}
+	return parts
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) sendChunk(chunk string, fields map[string]interface{}) error {
+	synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) {
+		if t.firstSendAt.IsZero() {
+			t.firstSendAt = now
+		}
+	})
+	atomic.AddInt64(&synthesizer.counters.sentChars, int64(utf8.RuneCountInString(chunk)))
+	if synthesizer.TextMode == TextModePlain {
+		chunk = EscapeText(chunk)
+	}
+	messageID := uuid.New().String()
+	synthesizer.statusMutex.Lock()
+	synthesizer.unacked[messageID] = true
+	synthesizer.statusMutex.Unlock()
+	frame := map[string]interface{}{
 		"session_id": synthesizer.SessionId,
-		"message_id": uuid.New().String(),
+		"message_id": messageID,
 		"action":     "ACTION_SYNTHESIS",
 		"data":       chunk,
-	})
+	}
+	synthesizer.statusMutex.Lock()
+	if synthesizer.speed != nil {
+		frame["speed"] = *synthesizer.speed
+	}
+	if synthesizer.volume != nil {
+		frame["volume"] = *synthesizer.volume
+	}
+	synthesizer.statusMutex.Unlock()
+	for k, v := range fields {
+		frame[k] = v
+	}
+	return synthesizer.writeJSON(frame)
 }
 
+// Complete tells the server no more text will be sent. Only the first successful call
+// writes the ACTION_COMPLETE frame, subsequent calls return nil; when the write fails,
+// e.g. with ErrWriteTimeout, the error is returned and a later call writes again.
+//
+// When no text was sent, neither through Send nor Text, Complete returns ErrNoText without
+// writing anything: the session stays open, send text and call Complete again or Close it.
 func (synthesizer *SpeechWsv2Synthesizer) Complete() error {
-	return synthesizer.conn.WriteJSON(map[string]interface{}{
+	if synthesizer.sentChars() == 0 && synthesizer.Text == "" {
+		return fmt.Errorf("session_id: %s, error: %w", synthesizer.SessionId, ErrNoText)
+	}
+	synthesizer.completeMutex.Lock()
+	defer synthesizer.completeMutex.Unlock()
+	if synthesizer.completed {
+		return nil
+	}
+	err := synthesizer.writeJSON(map[string]interface{}{
 		"session_id": synthesizer.SessionId,
 		"message_id": uuid.New().String(),
 		"action":     "ACTION_COMPLETE",
 		"data":       "",
 	})
+	if err != nil {
+		return err
+	}
+	synthesizer.completed = true
+	synthesizer.startDrainTimer()
+	return nil
+}
+
+// writeJSON serializes writes, the websocket connection supports one concurrent writer
+func (synthesizer *SpeechWsv2Synthesizer) writeJSON(v interface{}) error {
+	synthesizer.writeMutex.Lock()
+	defer synthesizer.writeMutex.Unlock()
+	if synthesizer.WriteTimeout <= 0 {
+		return synthesizer.conn.WriteJSON(v)
+	}
+	if err := synthesizer.conn.SetWriteDeadline(time.Now().Add(synthesizer.WriteTimeout)); err != nil {
+		return err
+	}
+	err := synthesizer.conn.WriteJSON(v)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		synthesizer.closeConn()
+		return fmt.Errorf("session_id: %s, error: %w after %s", synthesizer.SessionId, ErrWriteTimeout, synthesizer.WriteTimeout)
+	}
+	return err
 }
 
 func (synthesizer *SpeechWsv2Synthesizer) receive() {
 	defer func() {
 		// handle panic
 		synthesizer.genRecoverFunc()()
-		close(synthesizer.eventChan)
+		synthesizer.stopDrainTimer()
+		synthesizer.stopSessionTimer()
+		synthesizer.recordTiming(func(t *wsv2Timing, now time.Time) { t.endedAt = now })
+		if synthesizer.queue != nil {
+			synthesizer.queue.close()
+		} else {
+			close(synthesizer.eventChan)
+		}
 		close(synthesizer.receiveEnd)
 	}()
+	var partial []byte // start of a text frame split by a proxy
+	order := synthesizer.newInterleaver()
 	for {
+		synthesizer.waitResumed()
+		if synthesizer.IdleTimeout > 0 {
+			synthesizer.conn.SetReadDeadline(time.Now().Add(synthesizer.IdleTimeout))
+		}
 		optCode, data, err := synthesizer.conn.ReadMessage()
 		if err != nil {
-			synthesizer.onError(fmt.Errorf("SessionId: %s, error: %s", synthesizer.SessionId, err.Error()))
+			order.flush()
+			if synthesizer.closeExpected() {
+				break
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && synthesizer.IdleTimeout > 0 {
+				err = fmt.Errorf("%w: no frame for %s", ErrIdleTimeout, synthesizer.IdleTimeout)
+			}
+			synthesizer.onError(&SynthesisError{SessionId: synthesizer.SessionId, Err: err})
 			break
 		}
+		synthesizer.recordFrame(optCode == websocket.BinaryMessage)
+		atomic.AddInt64(&synthesizer.counters.bytesReceived, int64(len(data)))
 		if optCode == websocket.BinaryMessage {
+			var meta *AudioFrameMeta
+			if synthesizer.binaryHeaders {
+				var ok bool
+				if meta, data, ok = splitBinaryHeader(data); !ok {
+					synthesizer.debug("frame", len(data), "binary frame without header, taken as audio")
+				}
+			}
+			limited := false
+			if max := synthesizer.MaxAudioBytes; max > 0 {
+				if over := atomic.LoadInt64(&synthesizer.counters.audioBytes) + int64(len(data)) - max; over >= 0 {
+					data = data[:int64(len(data))-over]
+					limited = true
+				}
+			}
+			if atomic.AddInt64(&synthesizer.counters.audioFrames, 1) == 1 {
+				synthesizer.spanEvent("first_audio")
+			}
+			offset := atomic.AddInt64(&synthesizer.counters.audioBytes, int64(len(data))) - int64(len(data))
+			if synthesizer.collectAudio {
+				synthesizer.collector.addAudio(data)
+			}
 			msg := SpeechWsv2SynthesisResponse{SessionId: synthesizer.SessionId}
-			synthesizer.eventChan <- speechWsSynthesisEventv2{
-				t:   eventTypeWsAudioResultv2,
-				r:   &msg,
-				d:   data,
-				err: nil,
+			e := speechWsSynthesisEventv2{
+				t:    eventTypeWsAudioResultv2,
+				r:    &msg,
+				d:    data,
+				meta: meta,
+				err:  nil,
 			}
+			if !order.hold(e, offset) {
+				synthesizer.emit(e)
+			}
+			if limited {
+				order.flush()
+				synthesizer.shutdown(ErrAudioLimitReached)
+				break
+			}
+		}
+		if optCode != websocket.BinaryMessage && optCode != websocket.TextMessage {
+			synthesizer.unknownFrame(optCode, data)
+			continue
 		}
 		if optCode == websocket.TextMessage {
-			if synthesizer.Debug && synthesizer.DebugFunc != nil {
-				synthesizer.DebugFunc(string(data))
+			atomic.AddInt64(&synthesizer.counters.textFrames, 1)
+			synthesizer.debug("frame", len(data), string(data))
+			if partial != nil {
+				data = append(partial, data...)
+				partial = nil
 			}
-			msg := SpeechWsv2SynthesisResponse{}
-			err = json.Unmarshal(data, &msg)
+			if len(data) < maxPartialFramev2 && incompleteJSON(data) {
+				// websocket fragments are reassembled by ReadMessage, but misbehaving
+				// proxies may split a message into several
+				partial = data
+				continue
+			}
+			msg, err := synthesizer.decodeResponse(data)
 			if err != nil {
-				synthesizer.onError(fmt.Errorf("SessionId: %s, error: %s",
-					synthesizer.SessionId, err.Error()))
+				order.flush()
+				synthesizer.onError(&SynthesisError{SessionId: synthesizer.SessionId, Err: err})
 				break
 			}
 			msg.SessionId = synthesizer.SessionId
 			if msg.Code != 0 {
-				synthesizer.onError(fmt.Errorf("VoiceID: %s, error code %d, message: %s",
-					synthesizer.SessionId, msg.Code, msg.Message))
+				order.flush()
+				synthesizer.onError(&SynthesisError{SessionId: synthesizer.SessionId, Code: msg.Code, Message: msg.Message})
 				break
 			}
-			if msg.Final == 1 {
-				synthesizer.setStatus(eventTypeWsEndv2)
-				synthesizer.closeConn()
-				synthesizer.eventChan <- speechWsSynthesisEventv2{
-					t:   eventTypeWsEndv2,
-					r:   &msg,
+			if msg.Ready == 1 {
+				synthesizer.emit(speechWsSynthesisEventv2{
+					t:   eventTypeWsReadyv2,
+					r:   msg,
 					err: nil,
-				}
+				})
+				continue
+			}
+			synthesizer.ack(msg)
+			if !synthesizer.noSubtitleMerge {
+				synthesizer.collector.addSubtitles(msg.Result.Subtitles)
+			}
+			synthesizer.recordProgress(msg.Result.Subtitles)
+			if msg.Final == 1 {
+				order.flush()
+				synthesizer.end(msg)
 				break
 			}
-			synthesizer.eventChan <- speechWsSynthesisEventv2{
+			synthesizer.emit(speechWsSynthesisEventv2{
 				t:   eventTypeWsTextResultv2,
-				r:   &msg,
+				r:   msg,
 				err: nil,
-			}
+			})
+			order.text(msg.Result.Subtitles)
+			synthesizer.appendSubtitles(false)
 		}
 	}
 }
 
+// unknownFrame logs a frame of an unexpected message type and passes it to a
+// SpeechWsv2UnknownFrameListener
+func (synthesizer *SpeechWsv2Synthesizer) unknownFrame(messageType int, data []byte) {
+	synthesizer.debug("unknown_frame", len(data), fmt.Sprintf("unknown message type %d, %d bytes skipped", messageType, len(data)))
+	if _, ok := synthesizer.listener.(SpeechWsv2UnknownFrameListener); ok {
+		synthesizer.emit(speechWsSynthesisEventv2{t: eventTypeWsUnknownFramev2, d: data, op: messageType})
+	}
+}
+
+// end handles the final frame. Reading stops after it, the once guard keeps a duplicate
+// final frame, as sent by the server on some retries, from ending the session twice.
+func (synthesizer *SpeechWsv2Synthesizer) end(msg *SpeechWsv2SynthesisResponse) {
+	synthesizer.endOnce.Do(func() {
+		synthesizer.spanEvent("end")
+		synthesizer.setStatus(eventTypeWsEndv2)
+		synthesizer.closeConn()
+		synthesizer.appendSubtitles(true)
+		synthesizer.emit(speechWsSynthesisEventv2{
+			t:   eventTypeWsEndv2,
+			r:   msg,
+			err: nil,
+		})
+	})
+}
+
+// ack records msg as the acknowledgement of a sent chunk when its message_id is one of
+// theirs
+func (synthesizer *SpeechWsv2Synthesizer) ack(msg *SpeechWsv2SynthesisResponse) {
+	synthesizer.statusMutex.Lock()
+	acked := msg.MessageId != "" && synthesizer.unacked[msg.MessageId]
+	if acked {
+		delete(synthesizer.unacked, msg.MessageId)
+		synthesizer.acked = append(synthesizer.acked, msg.MessageId)
+	}
+	synthesizer.statusMutex.Unlock()
+	if _, ok := synthesizer.listener.(SpeechWsv2AckListener); ok && acked {
+		synthesizer.emit(speechWsSynthesisEventv2{t: eventTypeWsAckv2, r: msg})
+	}
+}
+
+// AckedMessageIDs returns the message_id of the frames written by Send that the server
+// echoed, in the order of the echoes. The protocol doesn't require the server to echo them:
+// when it answers with message_ids of its own, nothing is ever acknowledged.
+func (synthesizer *SpeechWsv2Synthesizer) AckedMessageIDs() []string {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	ids := make([]string, len(synthesizer.acked))
+	copy(ids, synthesizer.acked)
+	return ids
+}
+
+// Pause stops reading the socket until Resume, the frames sent meanwhile wait in the
+// connection buffers and are delivered after Resume, so no audio is lost. A frame already
+// being read is still delivered. The server gives up on a client that doesn't read for too
+// long, pause briefly.
+func (synthesizer *SpeechWsv2Synthesizer) Pause() {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	if synthesizer.paused == nil {
+		synthesizer.paused = make(chan struct{})
+	}
+}
+
+// Resume restarts reading after Pause
+func (synthesizer *SpeechWsv2Synthesizer) Resume() {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	if synthesizer.paused != nil {
+		close(synthesizer.paused)
+		synthesizer.paused = nil
+	}
+}
+
+// waitResumed blocks while the session is paused and not shut down
+func (synthesizer *SpeechWsv2Synthesizer) waitResumed() {
+	synthesizer.statusMutex.Lock()
+	paused := synthesizer.paused
+	synthesizer.statusMutex.Unlock()
+	if paused == nil {
+		return
+	}
+	select {
+	case <-paused:
+	case <-synthesizer.shutdownCh:
+	}
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) appendSubtitles(final bool) {
+	if _, ok := synthesizer.listener.(SpeechWsv2SubtitleListener); !ok {
+		return
+	}
+	if subs := synthesizer.collector.finalized(final); len(subs) > 0 {
+		synthesizer.emit(speechWsSynthesisEventv2{
+			t:    eventTypeWsSubtitleAppendedv2,
+			subs: subs,
+		})
+	}
+}
+
 func (synthesizer *SpeechWsv2Synthesizer) eventDispatch() {
 	defer func() {
 		// handle panic
 		synthesizer.genRecoverFunc()()
+		synthesizer.recordSessionEnd()
+		synthesizer.endSpan(synthesizer.sessionErr())
 		close(synthesizer.eventEnd)
 	}()
 	for e := range synthesizer.eventChan {
+		if atomic.LoadInt32(&synthesizer.aborted) == 1 {
+			continue
+		}
 		switch e.t {
 		case eventTypeWsStartv2:
 			synthesizer.listener.OnSynthesisStart(e.r)
 		case eventTypeWsEndv2:
+			synthesizer.dispatchProgress()
 			synthesizer.listener.OnSynthesisEnd(e.r)
 		case eventTypeWsAudioResultv2:
-			synthesizer.listener.OnAudioResult(e.d)
+			synthesizer.writeAudio(e.d)
+			if l, ok := synthesizer.listener.(SpeechWsv2CheckedListener); ok {
+				synthesizer.stopOnListenerError(l.OnAudioResultChecked(e.d))
+			} else {
+				synthesizer.listener.OnAudioResult(e.d)
+			}
+			synthesizer.dispatchAudioAt(e.d)
+			if l, ok := synthesizer.listener.(SpeechWsv2AudioMetaListener); ok && e.meta != nil {
+				l.OnAudioFrameMeta(*e.meta, e.d)
+			}
 		case eventTypeWsTextResultv2:
-			synthesizer.listener.OnTextResult(e.r)
+			if l, ok := synthesizer.listener.(SpeechWsv2CheckedListener); ok {
+				synthesizer.stopOnListenerError(l.OnTextResultChecked(e.r))
+			} else {
+				synthesizer.listener.OnTextResult(e.r)
+			}
+			synthesizer.dispatchProgress()
 		case eventTypeWsFailv2:
+			if synthesizer.failure == nil {
+				synthesizer.failure = e.err
+			}
 			synthesizer.listener.OnSynthesisFail(e.r, e.err)
+		case eventTypeWsReadyv2:
+			if l, ok := synthesizer.listener.(SpeechWsv2ReadyListener); ok {
+				l.OnReady(e.r)
+			}
+		case eventTypeWsAckv2:
+			synthesizer.listener.(SpeechWsv2AckListener).OnAck(e.r.MessageId)
+		case eventTypeWsUnknownFramev2:
+			synthesizer.listener.(SpeechWsv2UnknownFrameListener).OnUnknownFrame(e.op, e.d)
+		case eventTypeWsSubtitleAppendedv2:
+			l := synthesizer.listener.(SpeechWsv2SubtitleListener)
+			for _, sub := range e.subs {
+				l.OnSubtitleAppended(sub)
+			}
 		}
 	}
+	if atomic.LoadInt32(&synthesizer.aborted) == 0 {
+		synthesizer.flushAudio()
+	}
+}
+
+// dispatchAudioAt calls OnAudioResultAt with the playout offset of pcm audio, 16-bit mono
+// at SampleRate
+func (synthesizer *SpeechWsv2Synthesizer) dispatchAudioAt(data []byte) {
+	l, ok := synthesizer.listener.(SpeechWsv2TimedAudioListener)
+	if !ok || strings.ToLower(synthesizer.Codec) != "pcm" || synthesizer.SampleRate <= 0 {
+		return
+	}
+	offset := time.Duration(synthesizer.audioBytes) * time.Second / time.Duration(synthesizer.SampleRate*2)
+	synthesizer.audioBytes += int64(len(data))
+	l.OnAudioResultAt(offset, data)
+}
+
+// stopOnListenerError closes the session with the error of a SpeechWsv2CheckedListener
+func (synthesizer *SpeechWsv2Synthesizer) stopOnListenerError(err error) {
+	if err == nil {
+		return
+	}
+	synthesizer.shutdown(err)
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) transcoder() Transcoder {
+	if strings.ToLower(synthesizer.Codec) != "pcm" {
+		return nil
+	}
+	return synthesizer.Transcoder
+}
+
+// writeAudio runs in eventDispatch, a write failure ends the session, see onAudioError
+func (synthesizer *SpeechWsv2Synthesizer) writeAudio(data []byte) {
+	if synthesizer.AudioWriter == nil || synthesizer.audioErr != nil {
+		return
+	}
+	var err error
+	if t := synthesizer.transcoder(); t != nil {
+		data, err = t.Write(data)
+	}
+	if err == nil && len(data) > 0 {
+		_, err = synthesizer.AudioWriter.Write(data)
+	}
+	synthesizer.onAudioError(err)
+}
+
+// flushAudio flushes the Transcoder, then the AudioWriter if it has a Flush() error method
+func (synthesizer *SpeechWsv2Synthesizer) flushAudio() {
+	if synthesizer.AudioWriter == nil || synthesizer.audioErr != nil {
+		return
+	}
+	var err error
+	if t := synthesizer.transcoder(); t != nil {
+		var data []byte
+		data, err = t.Flush()
+		if err == nil && len(data) > 0 {
+			_, err = synthesizer.AudioWriter.Write(data)
+		}
+	}
+	if f, ok := synthesizer.AudioWriter.(interface{ Flush() error }); ok && err == nil {
+		err = f.Flush()
+	}
+	synthesizer.onAudioError(err)
+}
+
+// onAudioError ends the session on a failure of the Transcoder or AudioWriter: it is
+// terminated as Close does, so the read error that follows isn't reported, and the failure
+// is reported once to OnSynthesisFail and returned by Wait
+func (synthesizer *SpeechWsv2Synthesizer) onAudioError(err error) {
+	if err == nil {
+		return
+	}
+	synthesizer.audioErr = err
+	err = fmt.Errorf("session_id: %s, audio writer error: %w", synthesizer.SessionId, err)
+	synthesizer.shutdown(err)
+	if synthesizer.failure == nil {
+		synthesizer.failure = err
+	}
+	synthesizer.listener.OnSynthesisFail(&SpeechWsv2SynthesisResponse{SessionId: synthesizer.SessionId}, err)
 }
 
 // Wait Wait
 func (synthesizer *SpeechWsv2Synthesizer) Wait() error {
+	return synthesizer.WaitContext(context.Background())
+}
+
+// WaitContext waits until the synthesis ends or ctx is done. On cancellation the
+// connection is closed, the session goroutines are waited for and ctx.Err() is returned.
+// Without a session, before Prepare or after it failed, it returns ctx.Err() at once.
+func (synthesizer *SpeechWsv2Synthesizer) WaitContext(ctx context.Context) error {
 	synthesizer.mutex.Lock()
 	defer synthesizer.mutex.Unlock()
-	<-synthesizer.eventEnd
+	if !synthesizer.started {
+		return ctx.Err()
+	}
+	done := make(chan struct{})
+	go func() {
+		<-synthesizer.eventEnd
+		<-synthesizer.receiveEnd
+		close(done)
+	}()
+	select {
+	case <-done:
+		if err := synthesizer.terminationErr(); err != nil || !synthesizer.requireFinal {
+			return err
+		}
+		if !synthesizer.isTerminated() && !synthesizer.WasComplete() {
+			return fmt.Errorf("session_id: %s, error: %w", synthesizer.SessionId, ErrIncomplete)
+		}
+		return nil
+	case <-ctx.Done():
+		synthesizer.shutdown(ctx.Err())
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Close ends the session gracefully: reading stops, the frames already received are still
+// dispatched to the listener, the Transcoder and AudioWriter are flushed, and Close returns
+// once all of it is done, so the written audio is complete up to the last received frame.
+func (synthesizer *SpeechWsv2Synthesizer) Close() error {
+	if !synthesizer.started {
+		return nil
+	}
+	synthesizer.shutdown(nil)
 	<-synthesizer.receiveEnd
-	return nil
+	<-synthesizer.eventEnd
+	return synthesizer.audioErr
+}
+
+// Abort ends the session at once, the frames not yet dispatched are dropped and the
+// AudioWriter is not flushed.
+func (synthesizer *SpeechWsv2Synthesizer) Abort() {
+	if !synthesizer.started {
+		return
+	}
+	atomic.StoreInt32(&synthesizer.aborted, 1)
+	synthesizer.shutdown(nil)
+}
+
+// shutdown ends the session on the client's initiative, the connection is closed and the
+// resulting read error is not reported as a failure. The first reason is returned by Wait.
+func (synthesizer *SpeechWsv2Synthesizer) shutdown(reason error) bool {
+	synthesizer.statusMutex.Lock()
+	if synthesizer.terminated {
+		synthesizer.statusMutex.Unlock()
+		return false
+	}
+	synthesizer.terminated = true
+	synthesizer.termErr = reason
+	close(synthesizer.shutdownCh)
+	synthesizer.statusMutex.Unlock()
+	synthesizer.closeConn()
+	return true
+}
+
+// WasComplete reports whether the final frame arrived: when false once the session ended,
+// the audio received is truncated, whether the session failed, was closed by the client
+// or the server closed the connection early without reporting an error
+func (synthesizer *SpeechWsv2Synthesizer) WasComplete() bool {
+	return synthesizer.getStatus() == eventTypeWsEndv2
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) isTerminated() bool {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.terminated
+}
+
+// closeExpected reports whether a read error follows the end of the session: the client
+// terminated it, or the final frame arrived and the server closes its side too
+func (synthesizer *SpeechWsv2Synthesizer) closeExpected() bool {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.terminated || synthesizer.status == eventTypeWsEndv2
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) terminationErr() error {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	return synthesizer.termErr
 }
 
 func (synthesizer *SpeechWsv2Synthesizer) getStatus() int {
@@ -356,22 +1589,57 @@ func (synthesizer *SpeechWsv2Synthesizer) setStatus(status int) {
 }
 
 func (synthesizer *SpeechWsv2Synthesizer) onError(err error) {
+	atomic.AddInt64(&synthesizer.counters.errors, 1)
 	r := &SpeechWsv2SynthesisResponse{
 		SessionId: synthesizer.SessionId,
 	}
 	synthesizer.closeConn()
-	synthesizer.eventChan <- speechWsSynthesisEventv2{
+	synthesizer.emit(speechWsSynthesisEventv2{
 		t:   eventTypeWsFailv2,
 		r:   r,
 		err: err,
-	}
+	})
 }
 
 func (synthesizer *SpeechWsv2Synthesizer) buildURL(escape bool) string {
+	queryMap := synthesizer.queryParams(escape)
+	var keys []string
+	for k := range queryMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var queryStrBuffer bytes.Buffer
+	for _, k := range keys {
+		queryStrBuffer.WriteString(k)
+		queryStrBuffer.WriteString("=")
+		queryStrBuffer.WriteString(queryMap[k])
+		queryStrBuffer.WriteString("&")
+	}
+	rs := []rune(queryStrBuffer.String())
+	rsLen := len(rs)
+	queryStr := string(rs[0 : rsLen-1])
+	host := synthesizer.host
+	if host == "" {
+		host = wsHostv2
+	}
+	serverURL := fmt.Sprintf("%s%s", host, wsPathv2)
+	signURL := fmt.Sprintf("%s?%s", serverURL, queryStr)
+	return signURL
+}
+
+// queryParams returns the parameters of the request URL but the Signature, Text being
+// escaped when escape is set
+func (synthesizer *SpeechWsv2Synthesizer) queryParams(escape bool) map[string]string {
 	var queryMap = make(map[string]string)
 	queryMap["Action"] = synthesizer.action
 	queryMap["AppId"] = strconv.FormatInt(synthesizer.AppID, 10)
-	queryMap["SecretId"] = synthesizer.Credential.SecretId
+	if credential := synthesizer.Credential; credential != nil {
+		queryMap["SecretId"] = credential.SecretId
+		if credential.Token != "" {
+			queryMap["Token"] = credential.Token
+		}
+	}
 	queryMap["Timestamp"] = strconv.FormatInt(synthesizer.Timestamp, 10)
 	queryMap["Expired"] = strconv.FormatInt(synthesizer.Expired, 10)
 	if escape {
@@ -391,32 +1659,23 @@ func (synthesizer *SpeechWsv2Synthesizer) buildURL(escape bool) string {
 	queryMap["EmotionCategory"] = synthesizer.EmotionCategory
 	queryMap["EmotionIntensity"] = strconv.FormatInt(synthesizer.EmotionIntensity, 10)
 	queryMap["SegmentRate"] = strconv.FormatInt(synthesizer.SegmentRate, 10)
+	if method := synthesizer.SignatureAlgorithm.method(); method != "" {
+		queryMap["SignatureMethod"] = method
+	}
+	if len(synthesizer.lexicon) > 0 {
+		queryMap[lexiconParam] = encodeLexicon(synthesizer.lexicon)
+	}
 	for k, v := range synthesizer.ExtParam {
 		queryMap[k] = v
 	}
-	var keys []string
-	for k := range queryMap {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	var queryStrBuffer bytes.Buffer
-	for _, k := range keys {
-		queryStrBuffer.WriteString(k)
-		queryStrBuffer.WriteString("=")
-		queryStrBuffer.WriteString(queryMap[k])
-		queryStrBuffer.WriteString("&")
+	if synthesizer.queryMutator != nil {
+		synthesizer.queryMutator(queryMap)
 	}
-	rs := []rune(queryStrBuffer.String())
-	rsLen := len(rs)
-	queryStr := string(rs[0 : rsLen-1])
-	serverURL := fmt.Sprintf("%s%s", wsHostv2, wsPathv2)
-	signURL := fmt.Sprintf("%s?%s", serverURL, queryStr)
-	return signURL
+	return queryMap
 }
 
 func (synthesizer *SpeechWsv2Synthesizer) genWsSignature(signURL string, secretKey string) string {
-	hmac := hmac.New(sha1.New, []byte(secretKey))
+	hmac := hmac.New(synthesizer.SignatureAlgorithm.hash(), []byte(secretKey))
 	signURL = "GET" + signURL
 	hmac.Write([]byte(signURL))
 	encryptedStr := hmac.Sum([]byte(nil))
@@ -452,9 +1711,32 @@ func (synthesizer *SpeechWsv2Synthesizer) CloseConn() {
 	synthesizer.closeConn()
 }
 
-func (synthesizer *SpeechWsv2Synthesizer) closeConn() {
-	err := synthesizer.conn.Close()
-	if err != nil && synthesizer.Debug && synthesizer.DebugFunc != nil {
-		synthesizer.DebugFunc(fmt.Sprintf("%s %s", time.Now().String(), err.Error()))
+// CloseWithError closes the connection as CloseConn does and returns the error of the close,
+// if any, along with the reason the session was terminated as returned by Wait. Only the
+// first close reaches the connection, later calls return the same error.
+func (synthesizer *SpeechWsv2Synthesizer) CloseWithError() error {
+	if synthesizer.conn == nil {
+		return nil
 	}
+	closeErr := synthesizer.closeConn()
+	termErr := synthesizer.terminationErr()
+	switch {
+	case closeErr == nil:
+		return termErr
+	case termErr == nil:
+		return closeErr
+	default:
+		return fmt.Errorf("%w, close error: %s", termErr, closeErr.Error())
+	}
+}
+
+// closeConn closes the connection once and returns the error of that close
+func (synthesizer *SpeechWsv2Synthesizer) closeConn() error {
+	synthesizer.closeOnce.Do(func() {
+		synthesizer.closeErr = synthesizer.conn.Close()
+		if synthesizer.closeErr != nil {
+			synthesizer.debug("close_error", 0, fmt.Sprintf("%s %s", time.Now().String(), synthesizer.closeErr.Error()))
+		}
+	})
+	return synthesizer.closeErr
 }
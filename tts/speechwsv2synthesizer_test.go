@@ -0,0 +1,1347 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+func TestSubtitleUnmarshalTimestamps(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		wantBegin int64
+		wantEnd   int64
+	}{
+		{"integers", `{"BeginTime":120,"EndTime":360}`, 120, 360},
+		{"strings", `{"BeginTime":"120","EndTime":"360"}`, 120, 360},
+		{"floats", `{"BeginTime":120.0,"EndTime":360.0}`, 120, 360},
+		{"fractional floats rounded down", `{"BeginTime":120.9,"EndTime":360.5}`, 120, 360},
+		{"fractional strings rounded down", `{"BeginTime":"120.9","EndTime":" 360.99 "}`, 120, 360},
+		{"large integers", `{"BeginTime":9007199254740993,"EndTime":9007199254740995}`, 9007199254740993, 9007199254740995},
+		{"missing", `{"Text":"a"}`, 0, 0},
+		{"null and empty", `{"BeginTime":null,"EndTime":""}`, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sub Synthesisv2Subtitle
+			if err := json.Unmarshal([]byte(tt.json), &sub); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if sub.BeginTime != tt.wantBegin || sub.EndTime != tt.wantEnd {
+				t.Errorf("BeginTime, EndTime = %d, %d, want %d, %d", sub.BeginTime, sub.EndTime, tt.wantBegin, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestSubtitleUnmarshalKeepsOtherFields(t *testing.T) {
+	var sub Synthesisv2Subtitle
+	data := `{"Text":"你","Phoneme":"ni3","BeginTime":"0","EndTime":160.4,"BeginIndex":2,"EndIndex":3}`
+	if err := json.Unmarshal([]byte(data), &sub); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := Synthesisv2Subtitle{Text: "你", Phoneme: "ni3", BeginTime: 0, EndTime: 160, BeginIndex: 2, EndIndex: 3}
+	if sub != want {
+		t.Errorf("Unmarshal() = %+v, want %+v", sub, want)
+	}
+}
+
+func TestSubtitleUnmarshalInvalidTimestamp(t *testing.T) {
+	for _, data := range []string{`{"BeginTime":"soon"}`, `{"EndTime":true}`} {
+		var sub Synthesisv2Subtitle
+		if err := json.Unmarshal([]byte(data), &sub); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil, want an error", data)
+		}
+	}
+}
+
+func TestWaitContextCancelMidStream(t *testing.T) {
+	before := runtime.NumGoroutine()
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	for i := 0; i < 3; i++ {
+		conn.binary(pcm(640))
+	}
+	waitFor(t, "audio", func() bool { return listener.count("audio") == 3 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := s.WaitContext(ctx); err != context.Canceled {
+		t.Fatalf("WaitContext() = %v, want %v", err, context.Canceled)
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Errorf("OnSynthesisFail called with %v, want no failure on cancellation", failures)
+	}
+	checkGoroutines(t, before)
+}
+
+func TestWaitContextWithoutSession(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := s.WaitContext(ctx); err != context.Canceled {
+		t.Errorf("WaitContext() before Prepare = %v, want %v", err, context.Canceled)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() before Prepare = %v, want nil", err)
+	}
+	checkGoroutines(t, before)
+}
+
+func TestWaitContextAfterFailedPrepare(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {})
+	wsHostv2 = refusedHost(t)
+	before := runtime.NumGoroutine()
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.ConnectTimeout = time.Second
+	if err := s.Prepare(); err == nil {
+		t.Fatal("Prepare() error = nil with the host refusing")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.WaitContext(ctx); err != context.Canceled {
+		t.Errorf("WaitContext() = %v, want %v", err, context.Canceled)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+	checkGoroutines(t, before)
+	// the mutex was released: Reset doesn't block behind the waits
+	if err := s.Reset(); err != nil {
+		t.Errorf("Reset() error = %v", err)
+	}
+}
+
+func TestWaitContextDeadlineExceeded(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := s.Wait(); err != context.DeadlineExceeded {
+		t.Errorf("Wait() after WaitContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTranscoderWritesWAVToAudioWriter(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.binary(pcm(160))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if out.Len() != 44+480 || string(out.Bytes()[:4]) != "RIFF" {
+		t.Fatalf("AudioWriter got %d bytes starting with %q, want a 44 bytes WAV header and 480 bytes of pcm", out.Len(), out.Bytes()[:4])
+	}
+	if got := len(listener.audioBytes()); got != 480 {
+		t.Errorf("OnAudioResult got %d bytes, want the 480 bytes of pcm untranscoded", got)
+	}
+}
+
+func TestTranscoderSkippedForOtherCodecs(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.Codec = "mp3"
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	conn.binary([]byte("mp3 frame"))
+	conn.final()
+	s.Wait()
+	if out.String() != "mp3 frame" {
+		t.Errorf("AudioWriter got %q, want the mp3 untouched", out.String())
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestAudioWriterErrorReportedOnce(t *testing.T) {
+	writeErr := errors.New("disk full")
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.AudioWriter = failingWriter{writeErr}
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.binary(pcm(320))
+	err := s.Wait()
+	if !errors.Is(err, writeErr) {
+		t.Errorf("Wait() = %v, want the writer error", err)
+	}
+	failures := listener.failures()
+	if len(failures) != 1 || !errors.Is(failures[0], writeErr) {
+		t.Errorf("OnSynthesisFail got %v, want the writer error once", failures)
+	}
+	if closeErr := s.Close(); closeErr != writeErr {
+		t.Errorf("Close() = %v, want %v", closeErr, writeErr)
+	}
+}
+
+func TestCompleteWritesOnce(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.Text = "你好"
+	startFake(s, conn)
+	defer s.Abort()
+	for i := 0; i < 2; i++ {
+		if err := s.Complete(); err != nil {
+			t.Fatalf("Complete() #%d = %v", i+1, err)
+		}
+	}
+	if actions := conn.sentActions(); len(actions) != 1 || actions[0] != "ACTION_COMPLETE" {
+		t.Errorf("frames written = %v, want a single ACTION_COMPLETE", actions)
+	}
+}
+
+func TestCompleteRetriedAfterWriteFailure(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.Text = "你好"
+	startFake(s, conn)
+	defer s.Abort()
+	writeErr := errors.New("write failed")
+	conn.writeErr = writeErr
+	if err := s.Complete(); err != writeErr {
+		t.Fatalf("Complete() = %v, want %v", err, writeErr)
+	}
+	conn.mutex.Lock()
+	conn.writeErr = nil
+	conn.mutex.Unlock()
+	if err := s.Complete(); err != nil {
+		t.Fatalf("Complete() after a failure = %v", err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatalf("Complete() once completed = %v", err)
+	}
+	if actions := conn.sentActions(); len(actions) != 1 || actions[0] != "ACTION_COMPLETE" {
+		t.Errorf("frames written = %v, want a single ACTION_COMPLETE", actions)
+	}
+}
+
+// rotatingProvider returns a credential with a new token on every call
+type rotatingProvider struct{ calls int }
+
+func (p *rotatingProvider) GetCredential() (*common.Credential, error) {
+	p.calls++
+	return common.NewTokenCredential("AKIDexample", "secret", fmt.Sprintf("token-%d", p.calls)), nil
+}
+
+func TestCredentialProviderRotation(t *testing.T) {
+	provider := &rotatingProvider{}
+	s := NewSpeechWsv2SynthesizerWithProvider(0, provider, &recordListener{})
+	for i := 1; i <= 2; i++ {
+		signed, err := s.BuildSignedURL()
+		if err != nil {
+			t.Fatalf("BuildSignedURL() error = %v", err)
+		}
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) error = %v", signed, err)
+		}
+		if got, want := u.Query().Get("Token"), fmt.Sprintf("token-%d", i); got != want {
+			t.Errorf("request #%d signed with Token %q, want %q", i, got, want)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("GetCredential called %d times, want once per request", provider.calls)
+	}
+}
+
+func TestStaticCredentialPath(t *testing.T) {
+	credential := common.NewCredential("AKIDexample", "secret")
+	s := NewSpeechWsv2Synthesizer(0, credential, &recordListener{})
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	u, _ := url.Parse(signed)
+	if got := u.Query().Get("SecretId"); got != "AKIDexample" {
+		t.Errorf("SecretId = %q, want the static credential", got)
+	}
+}
+
+// readyListener records the OnReady calls besides the callbacks of recordListener
+type readyListener struct {
+	recordListener
+}
+
+func (l *readyListener) OnReady(r *SpeechWsv2SynthesisResponse) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record("ready")
+}
+
+func TestReadyMidStreamNotATextResult(t *testing.T) {
+	conn := newFakeConn()
+	listener := &readyListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.text(`{"code":0,"message":"success","ready":1}`)
+	conn.binary(pcm(320))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := listener.count("text"); n != 0 {
+		t.Errorf("OnTextResult called %d times, want none for the ready frame", n)
+	}
+	if n := listener.count("ready"); n != 1 {
+		t.Errorf("OnReady called %d times, want once", n)
+	}
+}
+
+func TestPrepareTimeoutCoversHandshake(t *testing.T) {
+	closed := make(chan struct{})
+	mockServer(t, func(conn *websocket.Conn) {
+		drain(conn)
+		close(closed)
+	})
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.ConnectTimeout = time.Second
+	s.PrepareTimeout = 50 * time.Millisecond
+	start := time.Now()
+	err := s.Prepare()
+	if !errors.Is(err, ErrPrepareTimeout) {
+		t.Fatalf("Prepare() = %v, want %v", err, ErrPrepareTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Prepare() returned after %v, want about the PrepareTimeout", elapsed)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("the connection upgraded was not closed")
+	}
+}
+
+func TestTextModePlainEscapes(t *testing.T) {
+	tests := []struct {
+		mode TextMode
+		want string
+	}{
+		{TextModePlain, "Tom &amp; Jerry &lt;3"},
+		{TextModeSSML, "Tom & Jerry <3"},
+	}
+	for _, tt := range tests {
+		conn := newFakeConn()
+		s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+		s.TextMode = tt.mode
+		startFake(s, conn)
+		if err := s.Send("Tom & Jerry <3"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		s.Abort()
+		if sent := conn.sent(); len(sent) != 1 || sent[0]["data"] != tt.want {
+			t.Errorf("mode %d sent %v, want data %q", tt.mode, sent, tt.want)
+		}
+	}
+}
+
+// gatedListener blocks OnAudioResult until the gate is closed, so that the frames received
+// meanwhile wait in the event queue
+type gatedListener struct {
+	recordListener
+	gate chan struct{}
+}
+
+func (l *gatedListener) OnAudioResult(data []byte) {
+	<-l.gate
+	l.recordListener.OnAudioResult(data)
+}
+
+func TestCloseFlushesBufferedAudio(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	listener := &gatedListener{gate: make(chan struct{})}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	for i := 0; i < 5; i++ {
+		conn.binary(pcm(320))
+	}
+	waitFor(t, "frames received", func() bool { return s.Stats().AudioFrames == 5 })
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	time.Sleep(10 * time.Millisecond)
+	close(listener.gate)
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() did not return")
+	}
+	if n := listener.count("audio"); n != 5 {
+		t.Errorf("OnAudioResult called %d times before Close returned, want the 5 frames received", n)
+	}
+	if out.Len() != 44+5*320 {
+		t.Errorf("AudioWriter got %d bytes, want the WAV header and the 5 frames", out.Len())
+	}
+}
+
+func TestAbortDropsBufferedAudio(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	listener := &gatedListener{gate: make(chan struct{})}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	for i := 0; i < 5; i++ {
+		conn.binary(pcm(320))
+	}
+	waitFor(t, "frames received", func() bool { return s.Stats().AudioFrames == 5 })
+	s.Abort()
+	close(listener.gate)
+	s.Wait()
+	if n := listener.count("audio"); n >= 5 {
+		t.Errorf("OnAudioResult called %d times, want the frames queued dropped", n)
+	}
+	if out.Len() >= 44 && string(out.Bytes()[:4]) == "RIFF" {
+		t.Errorf("AudioWriter got a WAV header, want the Transcoder left unflushed")
+	}
+}
+
+// fixedNow replaces Now for the rest of the test
+func fixedNow(t *testing.T, now time.Time) {
+	previous := Now
+	Now = func() time.Time { return now }
+	t.Cleanup(func() { Now = previous })
+}
+
+func TestBuildSignedURL(t *testing.T) {
+	fixedNow(t, time.Unix(1600000000, 0))
+	s := NewSpeechWsv2Synthesizer(1300000000, common.NewCredential("AKIDexample", "topsecretkey"), &recordListener{})
+	s.SessionId = "session-1"
+	s.Text = "你好 world"
+	s.VoiceType = 101001
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	if strings.Contains(signed, "topsecretkey") {
+		t.Errorf("BuildSignedURL() = %q contains the SecretKey", signed)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", signed, err)
+	}
+	if u.Scheme != "wss" || u.Host != "tts.cloud.tencent.com" || u.Path != wsPathv2 {
+		t.Errorf("BuildSignedURL() = %s://%s%s, want wss://tts.cloud.tencent.com%s", u.Scheme, u.Host, u.Path, wsPathv2)
+	}
+	query := u.Query()
+	want := map[string]string{
+		"Action":    defaultWsActionv2,
+		"AppId":     "1300000000",
+		"SecretId":  "AKIDexample",
+		"SessionId": "session-1",
+		"Text":      "你好 world",
+		"VoiceType": "101001",
+		"Codec":     "pcm",
+		"Timestamp": "1600000000",
+		"Expired":   "1600086400",
+	}
+	for k, v := range want {
+		if got := query.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(query.Get("Signature"))
+	if err != nil || len(signature) != sha1.Size {
+		t.Errorf("Signature %q is not a base64 HMAC-SHA1, error = %v", query.Get("Signature"), err)
+	}
+	if got, want := query.Get("Signature"), s.genWsSignature(s.buildURL(false), "topsecretkey"); got != want {
+		t.Errorf("Signature = %q, want %q", got, want)
+	}
+	if s.LastSignature() != query.Get("Signature") {
+		t.Errorf("LastSignature() = %q, want the signature of the URL", s.LastSignature())
+	}
+}
+
+func TestSignatureStableWithPinnedClock(t *testing.T) {
+	fixedNow(t, time.Unix(1600000000, 0))
+	sign := func() string {
+		s := NewSpeechWsv2Synthesizer(1300000000, common.NewCredential("AKIDexample", "topsecretkey"), &recordListener{})
+		s.SessionId = "session-1"
+		s.Text = "你好"
+		if _, err := s.BuildSignedURL(); err != nil {
+			t.Fatalf("BuildSignedURL() error = %v", err)
+		}
+		return s.LastSignature()
+	}
+	const golden = "1BFlq5s1QKw9h34fmvBXMRyr5lY="
+	for i := 0; i < 2; i++ {
+		if got := sign(); got != golden {
+			t.Errorf("signature = %q, want %q", got, golden)
+		}
+	}
+}
+
+func TestUserAgentHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, common.UserAgent},
+		{"WithUserAgent", []Option{WithUserAgent("my-app/2.0")}, "my-app/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockServer(t, func(conn *websocket.Conn) {
+				handshake(conn)
+				drain(conn)
+			})
+			s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, tt.opts...)
+			if err := s.Prepare(); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			s.Abort()
+			s.Wait()
+			if got := server.requests()[0].Header.Get("User-Agent"); got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendRejectsOversizedChunkByRunes(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.MaxChunkChars = 10
+	startFake(s, conn)
+	defer s.Abort()
+	// 10 runes are 30 bytes, within the limit
+	if err := s.Send(strings.Repeat("你", 10)); err != nil {
+		t.Fatalf("Send() of 10 runes error = %v", err)
+	}
+	if err := s.Send(strings.Repeat("你", 11)); !errors.Is(err, ErrChunkTooLong) {
+		t.Errorf("Send() of 11 runes = %v, want %v", err, ErrChunkTooLong)
+	}
+	if n := len(conn.sent()); n != 1 {
+		t.Errorf("%d frames written, want the oversized chunk not sent", n)
+	}
+}
+
+func TestSendAutoSplitsByRunes(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithAutoSplit())
+	s.MaxChunkChars = 4
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Send("你好世界，今天天气"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var got []string
+	for _, frame := range conn.sent() {
+		got = append(got, frame["data"].(string))
+	}
+	want := []string{"你好世界", "，今天天", "气"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("frames sent %q, want %q", got, want)
+	}
+}
+
+func TestModelTypeConstants(t *testing.T) {
+	if ModelTypeUnset != 0 || ModelTypeDefault != 1 {
+		t.Errorf("ModelTypeUnset, ModelTypeDefault = %d, %d, want 0, 1", ModelTypeUnset, ModelTypeDefault)
+	}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithModelType(ModelTypeDefault))
+	signed, err := s.BuildSignedURL()
+	if err != nil {
+		t.Fatalf("BuildSignedURL() error = %v", err)
+	}
+	u, _ := url.Parse(signed)
+	if got := u.Query().Get("ModelType"); got != "1" {
+		t.Errorf("ModelType = %q in the URL, want 1", got)
+	}
+
+	s = NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.ModelType = 1
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() with a raw ModelType 1 error = %v", err)
+	}
+	s.ModelType = 7
+	if err := s.Validate(); err == nil {
+		t.Error("Validate() with an unknown ModelType error = nil")
+	}
+}
+
+func TestFallbackHosts(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		drain(conn)
+	})
+	accepting := wsHostv2
+	wsHostv2 = refusedHost(t)
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithFallbackHosts([]string{accepting}))
+	s.ConnectTimeout = time.Second
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer s.Abort()
+	if got := s.EffectiveHost(); got != accepting {
+		t.Errorf("EffectiveHost() = %q, want the fallback %q", got, accepting)
+	}
+	if n := len(server.requests()); n != 1 {
+		t.Errorf("the fallback host got %d requests, want 1", n)
+	}
+}
+
+func TestFallbackHostsAllRefused(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {})
+	wsHostv2 = refusedHost(t)
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithFallbackHosts([]string{refusedHost(t)}))
+	s.ConnectTimeout = time.Second
+	if err := s.Prepare(); err == nil {
+		s.Abort()
+		t.Fatal("Prepare() error = nil with every host refusing")
+	}
+	if got := s.EffectiveHost(); got != "" {
+		t.Errorf("EffectiveHost() = %q, want empty", got)
+	}
+}
+
+func TestPauseStopsReading(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	waitFor(t, "first frame", func() bool { return listener.count("audio") == 1 })
+
+	s.Pause()
+	// the read in progress when pausing still delivers its frame
+	conn.binary(pcm(320))
+	waitFor(t, "frame being read", func() bool { return listener.count("audio") == 2 })
+	conn.binary(pcm(320))
+	conn.binary(pcm(320))
+	time.Sleep(50 * time.Millisecond)
+	if n := listener.count("audio"); n != 2 {
+		t.Errorf("%d frames delivered while paused, want 2", n)
+	}
+	if n := len(conn.frames); n != 2 {
+		t.Errorf("%d frames left unread while paused, want 2", n)
+	}
+
+	s.Resume()
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := len(listener.audioBytes()); n != 4*320 {
+		t.Errorf("%d bytes of audio delivered, want all 4 frames after Resume", n)
+	}
+}
+
+func TestIdleTimeoutSilentServer(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		// frames keep the session alive for a while, then the server goes silent
+		for i := 0; i < 3; i++ {
+			time.Sleep(idle / 2)
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","heartbeat":1}`))
+		}
+		drain(conn)
+	})
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, listener)
+	s.IdleTimeout = idle
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	start := time.Now()
+	s.Wait()
+	elapsed := time.Since(start)
+	if failures := listener.failures(); len(failures) != 1 || !errors.Is(failures[0], ErrIdleTimeout) {
+		t.Fatalf("OnSynthesisFail got %v, want %v", failures, ErrIdleTimeout)
+	}
+	// the heartbeats refreshed the deadline, the last one is sent 3*idle/2 in
+	if elapsed < 2*idle || elapsed > 3*idle/2+idle+time.Second {
+		t.Errorf("session failed after %v, want once the idle window followed the last heartbeat", elapsed)
+	}
+}
+
+// stoppingListener stops the session after limit audio frames, as a relay whose client
+// disconnected would
+type stoppingListener struct {
+	recordListener
+	limit int
+	err   error
+}
+
+func (l *stoppingListener) OnAudioResultChecked(data []byte) error {
+	l.OnAudioResult(data)
+	if l.count("audio") >= l.limit {
+		return l.err
+	}
+	return nil
+}
+
+func (l *stoppingListener) OnTextResultChecked(r *SpeechWsv2SynthesisResponse) error {
+	l.OnTextResult(r)
+	return nil
+}
+
+func TestCheckedListenerStopsSession(t *testing.T) {
+	clientGone := errors.New("client disconnected")
+	conn := newFakeConn()
+	listener := &stoppingListener{limit: 2, err: clientGone}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	for i := 0; i < 5; i++ {
+		conn.binary(pcm(320))
+	}
+	if err := s.Wait(); !errors.Is(err, clientGone) {
+		t.Fatalf("Wait() = %v, want %v", err, clientGone)
+	}
+	// the frames read before the session stopped are still dispatched
+	if n := listener.count("audio"); n < 2 || n > 5 {
+		t.Errorf("OnAudioResultChecked called %d times, want the session stopped after 2", n)
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Errorf("OnSynthesisFail called with %v, want it not called", failures)
+	}
+	conn.mutex.Lock()
+	closes := conn.closes
+	conn.mutex.Unlock()
+	if closes == 0 {
+		t.Error("the connection was not closed")
+	}
+}
+
+func TestCheckedListenerFlushesAudio(t *testing.T) {
+	clientGone := errors.New("client disconnected")
+	var out bytes.Buffer
+	conn := newFakeConn()
+	listener := &stoppingListener{limit: 1, err: clientGone}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.Codec = "pcm"
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.binary(pcm(160))
+	if err := s.Wait(); !errors.Is(err, clientGone) {
+		t.Fatalf("Wait() = %v, want %v", err, clientGone)
+	}
+	// the Transcoder was flushed: the WAV covers the audio dispatched
+	audio := listener.audioBytes()
+	if len(audio) == 0 || !bytes.Equal(out.Bytes(), append(WAVHeader(16000, len(audio)), audio...)) {
+		t.Errorf("AudioWriter got %d bytes, want the WAV of the %d bytes dispatched", out.Len(), len(audio))
+	}
+}
+
+func TestCloseWithErrorReportsCloseFailure(t *testing.T) {
+	closeErr := errors.New("close failed")
+	conn := newFakeConn()
+	conn.closeErr = closeErr
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	if err := s.CloseWithError(); err != closeErr {
+		t.Errorf("CloseWithError() = %v, want %v", err, closeErr)
+	}
+	if err := s.CloseWithError(); err != closeErr {
+		t.Errorf("CloseWithError() again = %v, want the same %v", err, closeErr)
+	}
+	s.CloseConn()
+	s.Wait()
+	conn.mutex.Lock()
+	closes := conn.closes
+	conn.mutex.Unlock()
+	if closes != 1 {
+		t.Errorf("connection closed %d times, want once", closes)
+	}
+}
+
+func TestCloseWithErrorJoinsTerminationReason(t *testing.T) {
+	closeErr := errors.New("close failed")
+	conn := newFakeConn()
+	conn.closeErr = closeErr
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	s.shutdown(ErrDrainTimeout)
+	err := s.CloseWithError()
+	if !errors.Is(err, ErrDrainTimeout) || !strings.Contains(err.Error(), closeErr.Error()) {
+		t.Errorf("CloseWithError() = %v, want %v along with the close error", err, ErrDrainTimeout)
+	}
+	s.Wait()
+}
+
+// audioOnlyListener overrides OnAudioResult only, NoopListener providing the rest
+type audioOnlyListener struct {
+	NoopListener
+	audio []byte
+}
+
+func (l *audioOnlyListener) OnAudioResult(data []byte) {
+	l.audio = append(l.audio, data...)
+}
+
+func TestEmbeddedNoopListener(t *testing.T) {
+	var _ SpeechWsv2SynthesisListener = NoopListener{}
+	conn := newFakeConn()
+	listener := &audioOnlyListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"a","BeginTime":0,"EndTime":10}]}}`)
+	conn.binary(pcm(160))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if len(listener.audio) != 480 {
+		t.Errorf("OnAudioResult got %d bytes, want 480", len(listener.audio))
+	}
+}
+
+func TestDuplicateFinalEndsOnce(t *testing.T) {
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.final()
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if n := listener.count("end"); n != 1 {
+		t.Errorf("OnSynthesisEnd called %d times, want once", n)
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Errorf("OnSynthesisFail called with %v", failures)
+	}
+	conn.mutex.Lock()
+	closes := conn.closes
+	conn.mutex.Unlock()
+	if closes != 1 {
+		t.Errorf("connection closed %d times, want once", closes)
+	}
+}
+
+func TestCompleteWithoutText(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Complete(); !errors.Is(err, ErrNoText) {
+		t.Fatalf("Complete() = %v, want %v", err, ErrNoText)
+	}
+	if n := len(conn.sent()); n != 0 {
+		t.Errorf("%d frames written, want none", n)
+	}
+	// the session stays usable
+	if err := s.Send("你好"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Errorf("Complete() after Send() = %v", err)
+	}
+	if actions := conn.sentActions(); len(actions) != 2 || actions[1] != "ACTION_COMPLETE" {
+		t.Errorf("frames written = %v, want the text then ACTION_COMPLETE", actions)
+	}
+}
+
+// timedListener records the offsets passed to OnAudioResultAt
+type timedListener struct {
+	recordListener
+	offsets []time.Duration
+	sizes   []int
+}
+
+func (l *timedListener) OnAudioResultAt(offset time.Duration, data []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.offsets = append(l.offsets, offset)
+	l.sizes = append(l.sizes, len(data))
+}
+
+func TestAudioResultOffsets(t *testing.T) {
+	conn := newFakeConn()
+	listener := &timedListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	startFake(s, conn)
+	// 16 kHz 16-bit mono pcm is 32 bytes per millisecond
+	for _, n := range []int{3200, 1600, 640, 3200} {
+		conn.binary(pcm(n))
+	}
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	want := []time.Duration{0, 100 * time.Millisecond, 150 * time.Millisecond, 170 * time.Millisecond}
+	if len(listener.offsets) != len(want) {
+		t.Fatalf("OnAudioResultAt offsets %v, want %v", listener.offsets, want)
+	}
+	for i := range want {
+		if listener.offsets[i] != want[i] {
+			t.Errorf("frame %d of %d bytes at %v, want %v", i, listener.sizes[i], listener.offsets[i], want[i])
+		}
+	}
+	if n := listener.count("audio"); n != 4 {
+		t.Errorf("OnAudioResult called %d times, want 4 as well", n)
+	}
+}
+
+func TestServerCloseAfterFinal(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(conn *websocket.Conn)
+	}{
+		{"close frame", func(conn *websocket.Conn) {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}},
+		{"abrupt", func(conn *websocket.Conn) { conn.UnderlyingConn().Close() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer(t, func(conn *websocket.Conn) {
+				handshake(conn)
+				conn.WriteMessage(websocket.BinaryMessage, pcm(320))
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+				tt.close(conn)
+			})
+			listener := &recordListener{}
+			s := NewSpeechWsv2Synthesizer(0, testCredential, listener)
+			if err := s.Prepare(); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			if err := s.Wait(); err != nil {
+				t.Errorf("Wait() = %v", err)
+			}
+			if failures := listener.failures(); len(failures) != 0 {
+				t.Errorf("OnSynthesisFail called with %v, want the close after final expected", failures)
+			}
+			if got := listener.eventList(); strings.Join(got, ",") != "start,audio 320,end" {
+				t.Errorf("events %v, want start, audio and end", got)
+			}
+		})
+	}
+}
+
+// sessionListener reads Session from its callbacks, as a listener shared between
+// synthesizers would
+type sessionListener struct {
+	NoopListener
+	synthesizer *SpeechWsv2Synthesizer
+	sessions    []SessionInfo
+}
+
+func (l *sessionListener) OnAudioResult(data []byte) {
+	l.sessions = append(l.sessions, *l.synthesizer.Session())
+}
+
+func TestSessionDuringCallbacks(t *testing.T) {
+	conn := newFakeConn()
+	listener := &sessionListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	listener.synthesizer = s
+	s.SessionId = "session-42"
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	want := SessionInfo{SessionId: "session-42", RequestId: "test-request"}
+	if len(listener.sessions) != 1 || listener.sessions[0] != want {
+		t.Errorf("Session() from OnAudioResult = %+v, want %+v", listener.sessions, want)
+	}
+}
+
+func TestSendWriteTimeout(t *testing.T) {
+	conn := newFakeConn()
+	conn.stallWrites = true
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.WriteTimeout = 50 * time.Millisecond
+	startFake(s, conn)
+	start := time.Now()
+	err := s.Send("你好")
+	if !errors.Is(err, ErrWriteTimeout) {
+		t.Fatalf("Send() = %v, want %v", err, ErrWriteTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() returned after %v, want about the WriteTimeout", elapsed)
+	}
+	conn.mutex.Lock()
+	closes := conn.closes
+	conn.mutex.Unlock()
+	if closes == 0 {
+		t.Error("the connection was not closed on the write timeout")
+	}
+	s.Wait()
+}
+
+func TestSendWithVoiceEncodesFrame(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.SendWithVoice("你好", 101002); err != nil {
+		t.Fatalf("SendWithVoice() error = %v", err)
+	}
+	if err := s.SendWithVoice("再见", 999999); err == nil {
+		t.Error("SendWithVoice() with an unknown voice error = nil")
+	}
+	sent := conn.sent()
+	if len(sent) != 1 || sent[0]["data"] != "你好" || sent[0]["voice_type"] != float64(101002) {
+		t.Errorf("frames written = %v, want one carrying voice_type 101002", sent)
+	}
+}
+
+func TestSetSpeedAppliesToLaterSends(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Send("一"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSpeed(1.5); err != nil {
+		t.Fatalf("SetSpeed() error = %v", err)
+	}
+	if err := s.SetVolume(-3); err != nil {
+		t.Fatalf("SetVolume() error = %v", err)
+	}
+	if err := s.Send("二"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSpeed(7); err == nil {
+		t.Error("SetSpeed(7) error = nil")
+	}
+	if err := s.SetVolume(11); err == nil {
+		t.Error("SetVolume(11) error = nil")
+	}
+	if err := s.Send("三"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := conn.sent()
+	if len(sent) != 3 {
+		t.Fatalf("%d frames written, want 3", len(sent))
+	}
+	if _, ok := sent[0]["speed"]; ok {
+		t.Errorf("frame before SetSpeed = %v, want no speed", sent[0])
+	}
+	for _, frame := range sent[1:] {
+		if frame["speed"] != 1.5 || frame["volume"] != float64(-3) {
+			t.Errorf("frame = %v, want speed 1.5 and volume -3", frame)
+		}
+	}
+}
+
+func TestPrepareRejectsIncompleteCredential(t *testing.T) {
+	tests := []struct {
+		name       string
+		credential *common.Credential
+		want       string
+	}{
+		{"nil", nil, "credential is nil"},
+		{"empty SecretId", common.NewCredential("", "secret"), "SecretId is empty"},
+		{"empty SecretKey", common.NewCredential("AKIDexample", ""), "SecretKey is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSpeechWsv2Synthesizer(0, tt.credential, &recordListener{})
+			if err := s.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+			// refused before dialing: the default host is never reached
+			if err := s.Prepare(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Prepare() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildURLWithoutCredential(t *testing.T) {
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	if u := s.buildURL(false); strings.Contains(u, "SecretId") {
+		t.Errorf("buildURL() = %q, want no SecretId", u)
+	}
+}
+
+// ackListener records the message_ids passed to OnAck
+type ackListener struct {
+	recordListener
+	mutex sync.Mutex
+	ids   []string
+}
+
+func (l *ackListener) OnAck(messageID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.ids = append(l.ids, messageID)
+}
+
+func (l *ackListener) acks() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.ids...)
+}
+
+func TestAcksMatchSentMessageIDs(t *testing.T) {
+	conn := newFakeConn()
+	l := &ackListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, l)
+	startFake(s, conn)
+	defer s.Abort()
+	for _, chunk := range []string{"你好", "世界"} {
+		if err := s.Send(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := conn.sent()
+	first, second := fmt.Sprint(sent[0]["message_id"]), fmt.Sprint(sent[1]["message_id"])
+	if first == second {
+		t.Fatalf("chunks share the message_id %q", first)
+	}
+	// echoes in another order, one the client never sent and a repeated one
+	for _, id := range []string{second, "server-own-id", first, second} {
+		conn.text(`{"code":0,"message":"success","message_id":%q}`, id)
+	}
+	waitFor(t, "text frames", func() bool { return l.count("text") == 4 })
+
+	want := []string{second, first}
+	if got := s.AckedMessageIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AckedMessageIDs() = %q, want %q", got, want)
+	}
+	if got := l.acks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OnAck ids = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareRejectsTextOverURLLimit(t *testing.T) {
+	// "你" is 9 bytes once escaped
+	fits := strings.Repeat("你", maxQueryTextBytesv2/9)
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.Text = fits
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() with %d escaped bytes of Text error = %v", len(fits)*3, err)
+	}
+
+	s.Text = fits + "你"
+	err := s.Prepare()
+	if !errors.Is(err, ErrTextTooLong) {
+		t.Fatalf("Prepare() error = %v, want %v", err, ErrTextTooLong)
+	}
+	if !strings.Contains(err.Error(), "Send") {
+		t.Errorf("Prepare() error = %q, want guidance to use Send", err)
+	}
+}
+
+func TestDoneChannelStopsAllSessions(t *testing.T) {
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	sessions := make([]*SpeechWsv2Synthesizer, 3)
+	for i := range sessions {
+		s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithDoneChannel(done))
+		s.SessionId = fmt.Sprintf("session-%d", i)
+		startFake(s, newFakeConn())
+		sessions[i] = s
+	}
+	close(done)
+	for _, s := range sessions {
+		if err := s.Wait(); !errors.Is(err, ErrDone) {
+			t.Errorf("%s: Wait() = %v, want %v", s.SessionId, err, ErrDone)
+		}
+	}
+	checkGoroutines(t, before)
+
+	// a session started once done is closed stops right away
+	late := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithDoneChannel(done))
+	startFake(late, newFakeConn())
+	if err := late.Wait(); !errors.Is(err, ErrDone) {
+		t.Errorf("late session: Wait() = %v, want %v", err, ErrDone)
+	}
+}
+
+func TestServerCloseBeforeFinal(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		conn.WriteMessage(websocket.BinaryMessage, pcm(320))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		drain(conn)
+	})
+	for _, requireFinal := range []bool{false, true} {
+		var opts []Option
+		if requireFinal {
+			opts = append(opts, WithRequireFinal())
+		}
+		listener := &recordListener{}
+		s := NewSpeechWsv2Synthesizer(0, testCredential, listener, opts...)
+		if err := s.Prepare(); err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		err := s.Wait()
+		if requireFinal && !errors.Is(err, ErrIncomplete) {
+			t.Errorf("Wait() with WithRequireFinal = %v, want %v", err, ErrIncomplete)
+		}
+		if !requireFinal && err != nil {
+			t.Errorf("Wait() = %v, want nil", err)
+		}
+		if s.WasComplete() {
+			t.Error("WasComplete() = true without the final frame")
+		}
+		if n := listener.count("audio"); n != 1 {
+			t.Errorf("%d audio frames delivered, want the one sent", n)
+		}
+	}
+}
+
+func TestRequireFinalAfterClose(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithRequireFinal())
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	s.Close()
+	if err := s.Wait(); errors.Is(err, ErrIncomplete) {
+		t.Errorf("Wait() after Close = %v, want no %v", err, ErrIncomplete)
+	}
+	if s.WasComplete() {
+		t.Error("WasComplete() = true after Close")
+	}
+}
+
+func TestNetDialer(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		drain(conn)
+	})
+	local, err := net.ResolveTCPAddr("tcp", refusedHost(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var controlled []string
+	d := &net.Dialer{
+		LocalAddr: local,
+		Control: func(network, address string, c syscall.RawConn) error {
+			controlled = append(controlled, address)
+			return nil
+		},
+	}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithNetDialer(d))
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer s.Abort()
+	if want := []string{wsHostv2}; !reflect.DeepEqual(controlled, want) {
+		t.Errorf("Control called for %v, want %v", controlled, want)
+	}
+	if reqs := server.requests(); len(reqs) != 1 || reqs[0].RemoteAddr != local.String() {
+		t.Errorf("server got requests %v, want one from %s", reqs, local)
+	}
+}
+
+func TestNetDialerRejectsNonTCPLocalAddr(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		drain(conn)
+	})
+	d := &net.Dialer{LocalAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithNetDialer(d))
+	if err := s.Prepare(); err == nil {
+		s.Abort()
+		t.Fatal("Prepare() error = nil with a UDP LocalAddr")
+	}
+	if n := len(server.requests()); n != 0 {
+		t.Errorf("server got %d requests, want the dialer rejected before dialing", n)
+	}
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	credential := common.NewCredential("AKIDz8krbsJ5yKBZQpn74WFkmLPx3gnPhESA", "secret-key")
+	credential.Token = "session-token"
+	s := NewSpeechWsv2Synthesizer(1300000000, credential, &recordListener{}, WithModelType(1))
+	s.VoiceType = 101001
+	s.Codec = "mp3"
+	s.SampleRate = 16000
+	s.Speed = 1.5
+	s.Volume = -2
+	s.EmotionCategory = "happy"
+	s.EmotionIntensity = 150
+	s.Text = "private text"
+
+	snapshot := s.ConfigSnapshot()
+	want := map[string]string{
+		"AppId":            "1300000000",
+		"VoiceType":        "101001",
+		"Codec":            "mp3",
+		"SampleRate":       "16000",
+		"Speed":            "1.5",
+		"Volume":           "-2",
+		"EmotionCategory":  "happy",
+		"EmotionIntensity": "150",
+		"ModelType":        "1",
+		"SecretId":         "****hESA",
+		"Host":             wsHostv2,
+	}
+	for key, value := range want {
+		if got, ok := snapshot[key]; !ok || got != value {
+			t.Errorf("ConfigSnapshot()[%q] = %q, want %q", key, got, value)
+		}
+	}
+	for _, key := range []string{"Text", "Token", "SecretKey", "Signature"} {
+		if value, ok := snapshot[key]; ok {
+			t.Errorf("ConfigSnapshot()[%q] = %q, want it left out", key, value)
+		}
+	}
+	for key, value := range snapshot {
+		if strings.Contains(value, credential.SecretId) || strings.Contains(value, credential.SecretKey) {
+			t.Errorf("ConfigSnapshot()[%q] = %q leaks the credential", key, value)
+		}
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	for secret, want := range map[string]string{
+		"":                     "****",
+		"short":                "****",
+		"AKIDexample":          "****mple",
+		"AKIDz8krbsJ5yKBZQpn7": "****Qpn7",
+	} {
+		if got := maskSecret(secret); got != want {
+			t.Errorf("maskSecret(%q) = %q, want %q", secret, got, want)
+		}
+	}
+}
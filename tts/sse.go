@@ -0,0 +1,90 @@
+package tts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// SSEWriter relays a synthesis to a browser EventSource as Server-Sent Events, flushed
+// one by one:
+//
+//	event: audio     data: the base64 audio of a frame
+//	event: subtitle  data: the subtitles of a frame, formatted as SubtitlesToJSON
+//	event: end       data: {}
+//	event: error     data: {"message": ...}
+//
+// Use it as the listener of the session. It implements SpeechWsv2CheckedListener, so a
+// write failing once the client disconnected ends the session as Abort does; pass the
+// Done channel of the request context to WithDoneChannel to stop without waiting for a
+// write to fail:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		s := tts.NewSpeechWsv2Synthesizer(appID, credential, tts.NewSSEWriter(w),
+//			tts.WithDoneChannel(r.Context().Done()))
+//		...
+//	}
+type SSEWriter struct {
+	mutex sync.Mutex
+	w     http.ResponseWriter
+}
+
+// NewSSEWriter creates instance of SSEWriter, setting the SSE response headers of w
+func NewSSEWriter(w http.ResponseWriter) *SSEWriter {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	return &SSEWriter{w: w}
+}
+
+// event writes an event and flushes it, data must not contain newlines
+func (s *SSEWriter) event(name string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (s *SSEWriter) OnSynthesisStart(*SpeechWsv2SynthesisResponse) {}
+
+func (s *SSEWriter) OnSynthesisEnd(*SpeechWsv2SynthesisResponse) {
+	s.event("end", []byte("{}"))
+}
+
+func (s *SSEWriter) OnAudioResult(data []byte) {
+	s.OnAudioResultChecked(data)
+}
+
+func (s *SSEWriter) OnTextResult(r *SpeechWsv2SynthesisResponse) {
+	s.OnTextResultChecked(r)
+}
+
+func (s *SSEWriter) OnSynthesisFail(r *SpeechWsv2SynthesisResponse, err error) {
+	data, _ := json.Marshal(map[string]string{"message": err.Error()})
+	s.event("error", data)
+}
+
+// OnAudioResultChecked writes an audio event
+func (s *SSEWriter) OnAudioResultChecked(data []byte) error {
+	return s.event("audio", []byte(base64.StdEncoding.EncodeToString(data)))
+}
+
+// OnTextResultChecked writes a subtitle event, frames without subtitles are skipped
+func (s *SSEWriter) OnTextResultChecked(r *SpeechWsv2SynthesisResponse) error {
+	if len(r.Result.Subtitles) == 0 {
+		return nil
+	}
+	data, err := SubtitlesToJSON(r.Result.Subtitles)
+	if err != nil {
+		return err
+	}
+	return s.event("subtitle", data)
+}
@@ -0,0 +1,97 @@
+package tts
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// flushRecorder is a ResponseWriter recording the body and counting the flushes
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+// sseEvent is an event parsed from an SSE stream
+type sseEvent struct {
+	name, data string
+}
+
+// parseSSE splits an SSE stream into its events, failing on malformed framing
+func parseSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	if !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("stream %q doesn't end with a blank line", body)
+	}
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		lines := strings.Split(block, "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "event: ") || !strings.HasPrefix(lines[1], "data: ") {
+			t.Fatalf("malformed event %q", block)
+		}
+		events = append(events, sseEvent{strings.TrimPrefix(lines[0], "event: "), strings.TrimPrefix(lines[1], "data: ")})
+	}
+	return events
+}
+
+func TestSSEWriterFraming(t *testing.T) {
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, NewSSEWriter(rec))
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`)
+	conn.text(`{"code":0,"message":"success"}`)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	events := parseSSE(t, rec.Body.String())
+	want := []sseEvent{
+		{"audio", base64.StdEncoding.EncodeToString(pcm(320))},
+		{"subtitle", `[{"text":"你","begin_ms":0,"end_ms":160,"begin_index":0,"end_index":1}]`},
+		{"end", "{}"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, events[i], want[i])
+		}
+	}
+	if rec.flushes != len(want) {
+		t.Errorf("%d flushes, want one per event", rec.flushes)
+	}
+}
+
+// disconnectedWriter fails every write as a ResponseWriter of a gone client
+type disconnectedWriter struct {
+	http.ResponseWriter
+}
+
+var errClientGone = errors.New("client disconnected")
+
+func (disconnectedWriter) Write([]byte) (int, error) { return 0, errClientGone }
+
+func TestSSEWriterStopsOnDisconnect(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, NewSSEWriter(disconnectedWriter{httptest.NewRecorder()}))
+	startFake(s, conn)
+	conn.binary(pcm(320))
+	if err := s.Wait(); !errors.Is(err, errClientGone) {
+		t.Errorf("Wait() = %v, want the write error %v", err, errClientGone)
+	}
+}
@@ -0,0 +1,141 @@
+package tts
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ssmlUnit is a top level child of <speak>, copied as written
+type ssmlUnit struct {
+	raw     string
+	element string // name of the element, empty for text
+}
+
+// SplitSSML splits an SSML document into standalone documents whose content, between
+// <speak ...> and </speak>, is at most maxChars runes, tags included. It cuts between the
+// children of <speak> only, so nested elements such as <prosody> or <phoneme> stay whole;
+// text directly under <speak> may also be cut at sentence ends, or between runes as a last
+// resort. Every chunk repeats the attributes of the original <speak>. It fails on malformed
+// SSML and when a single child element exceeds maxChars.
+func SplitSSML(ssml string, maxChars int) ([]string, error) {
+	if maxChars <= 0 {
+		return nil, fmt.Errorf("maxChars must be positive")
+	}
+	open, units, err := parseSSML(ssml)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []string
+	var content strings.Builder
+	size := 0
+	flush := func() {
+		if strings.TrimSpace(content.String()) != "" {
+			chunks = append(chunks, open+content.String()+"</speak>")
+		}
+		content.Reset()
+		size = 0
+	}
+	add := func(raw string) {
+		n := utf8.RuneCountInString(raw)
+		if size+n > maxChars {
+			flush()
+		}
+		content.WriteString(raw)
+		size += n
+	}
+	for _, unit := range units {
+		n := utf8.RuneCountInString(unit.raw)
+		if n <= maxChars {
+			add(unit.raw)
+			continue
+		}
+		if unit.element != "" {
+			return nil, fmt.Errorf("<%s> element of %d chars exceeds maxChars %d", unit.element, n, maxChars)
+		}
+		for _, sentence := range splitSentences(unit.raw) {
+			for _, piece := range splitEscapedText(sentence, maxChars) {
+				add(piece)
+			}
+		}
+	}
+	flush()
+	return chunks, nil
+}
+
+// parseSSML returns the <speak> start tag as written and the children of the root
+func parseSSML(ssml string) (string, []ssmlUnit, error) {
+	dec := xml.NewDecoder(strings.NewReader(ssml))
+	var open string
+	var units []ssmlUnit
+	var unitStart int64
+	var unitName string
+	depth := 0
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid SSML: %s", err.Error())
+		}
+		after := dec.InputOffset()
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch depth {
+			case 0:
+				if t.Name.Local != "speak" {
+					return "", nil, fmt.Errorf("invalid SSML: root element is <%s>, want <speak>", t.Name.Local)
+				}
+				if open != "" {
+					return "", nil, fmt.Errorf("invalid SSML: several <speak> elements")
+				}
+				open = ssml[before:after]
+			case 1:
+				unitStart, unitName = before, t.Name.Local
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 1 {
+				units = append(units, ssmlUnit{raw: ssml[unitStart:after], element: unitName})
+			}
+		case xml.CharData, xml.Comment:
+			if depth == 1 {
+				units = append(units, ssmlUnit{raw: ssml[before:after]})
+			}
+		}
+	}
+	if open == "" {
+		return "", nil, fmt.Errorf("invalid SSML: missing <speak> element")
+	}
+	// a self-closing <speak/> is reported as a start tag ending with "/>"
+	open = strings.TrimSuffix(strings.TrimSuffix(open, "/>"), ">") + ">"
+	return open, units, nil
+}
+
+// splitEscapedText cuts XML text into pieces of at most max runes without splitting an
+// entity such as &amp;
+func splitEscapedText(text string, max int) []string {
+	var pieces []string
+	for utf8.RuneCountInString(text) > max {
+		end, n := 0, 0
+		for n < max {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+			n++
+		}
+		if amp := strings.LastIndexByte(text[:end], '&'); amp > 0 && !strings.Contains(text[amp:end], ";") {
+			end = amp
+		}
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	if text != "" {
+		pieces = append(pieces, text)
+	}
+	return pieces
+}
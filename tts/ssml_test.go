@@ -0,0 +1,89 @@
+package tts
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitSSML(t *testing.T) {
+	phoneme := `<phoneme alphabet="py" ph="zhong1">中</phoneme>`
+	prosody := `<prosody rate="slow"><emphasis>慢慢</emphasis>` + phoneme + `</prosody>`
+	tests := []struct {
+		name     string
+		ssml     string
+		maxChars int
+		want     []string
+	}{
+		{
+			"fits",
+			`<speak>你好` + phoneme + `</speak>`,
+			100,
+			[]string{`<speak>你好` + phoneme + `</speak>`},
+		},
+		{
+			"nested elements kept whole",
+			`<speak version="1.0">你好` + prosody + `再见</speak>`,
+			utf8.RuneCountInString(prosody),
+			[]string{
+				`<speak version="1.0">你好</speak>`,
+				`<speak version="1.0">` + prosody + `</speak>`,
+				`<speak version="1.0">再见</speak>`,
+			},
+		},
+		{
+			"text cut at sentence ends",
+			`<speak>第一句。第二句。</speak>`,
+			4,
+			[]string{`<speak>第一句。</speak>`, `<speak>第二句。</speak>`},
+		},
+		{
+			"entities not split",
+			`<speak>ab&amp;cd</speak>`,
+			5,
+			[]string{`<speak>ab</speak>`, `<speak>&amp;</speak>`, `<speak>cd</speak>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := SplitSSML(tt.ssml, tt.maxChars)
+			if err != nil {
+				t.Fatalf("SplitSSML() error = %v", err)
+			}
+			if !reflect.DeepEqual(chunks, tt.want) {
+				t.Errorf("SplitSSML() = %q, want %q", chunks, tt.want)
+			}
+			for _, chunk := range chunks {
+				if err := xml.Unmarshal([]byte(chunk), new(struct{})); err != nil {
+					t.Errorf("chunk %q is not well-formed: %v", chunk, err)
+				}
+				content := chunk[strings.IndexByte(chunk, '>')+1 : len(chunk)-len("</speak>")]
+				if n := utf8.RuneCountInString(content); n > tt.maxChars {
+					t.Errorf("chunk %q holds %d chars, max %d", chunk, n, tt.maxChars)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSSMLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		ssml     string
+		maxChars int
+	}{
+		{"element too long", `<speak><prosody rate="slow">` + strings.Repeat("长", 20) + `</prosody></speak>`, 10},
+		{"unclosed tag", `<speak><prosody>你好</speak>`, 100},
+		{"wrong root", `<voice>你好</voice>`, 100},
+		{"no maxChars", `<speak>你好</speak>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SplitSSML(tt.ssml, tt.maxChars); err == nil {
+				t.Errorf("SplitSSML(%q, %d) error = nil", tt.ssml, tt.maxChars)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package tts
+
+import "github.com/showntop/tencentcloud-speech-sdk-go/common"
+
+// SynthesizeStream synthesizes text, passing each audio frame to sendAudio and the
+// subtitles of each text frame to sendSubtitles, if not nil, e.g. to relay them on the
+// stream of a gRPC call without implementing a listener. The callbacks run one at a time
+// in frame order. It returns once the synthesis ended, with the first error of a callback,
+// typically the client cancelled, which ends the session at once, or the failure of the
+// synthesis. Text longer than MaxChunkChars is split.
+func SynthesizeStream(appID int64, credential *common.Credential, text string,
+	sendAudio func([]byte) error, sendSubtitles func([]Synthesisv2Subtitle) error, opts ...Option) error {
+	listener := &streamListener{sendAudio: sendAudio, sendSubtitles: sendSubtitles}
+	synthesizer := NewSpeechWsv2Synthesizer(appID, credential, listener, append([]Option{WithAutoSplit()}, opts...)...)
+	if err := synthesizeAll(synthesizer, text); err != nil {
+		return err
+	}
+	return listener.failure()
+}
+
+// streamListener is the listener of SynthesizeStream
+type streamListener struct {
+	failureListener
+	sendAudio     func([]byte) error
+	sendSubtitles func([]Synthesisv2Subtitle) error
+}
+
+func (l *streamListener) OnAudioResultChecked(data []byte) error {
+	return l.sendAudio(data)
+}
+
+func (l *streamListener) OnTextResultChecked(r *SpeechWsv2SynthesisResponse) error {
+	if l.sendSubtitles == nil || len(r.Result.Subtitles) == 0 {
+		return nil
+	}
+	return l.sendSubtitles(r.Result.Subtitles)
+}
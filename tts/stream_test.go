@@ -0,0 +1,76 @@
+package tts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSynthesizeStream(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		for {
+			var frame map[string]interface{}
+			if err := conn.ReadJSON(&frame); err != nil || frame["action"] == "ACTION_COMPLETE" {
+				break
+			}
+		}
+		conn.WriteMessage(websocket.BinaryMessage, pcm(320))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`))
+		conn.WriteMessage(websocket.BinaryMessage, pcm(160))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"message":"success","final":1}`))
+		drain(conn)
+	})
+	var audio, subtitles int
+	err := SynthesizeStream(0, testCredential, "你好",
+		func(data []byte) error { audio += len(data); return nil },
+		func(subs []Synthesisv2Subtitle) error { subtitles += len(subs); return nil })
+	if err != nil {
+		t.Fatalf("SynthesizeStream() error = %v", err)
+	}
+	if audio != 480 || subtitles != 1 {
+		t.Errorf("sent %d bytes of audio and %d subtitles, want 480 and 1", audio, subtitles)
+	}
+}
+
+func TestSynthesizeStreamSendError(t *testing.T) {
+	closed := make(chan struct{})
+	mockServer(t, func(conn *websocket.Conn) {
+		defer close(closed)
+		handshake(conn)
+		for {
+			var frame map[string]interface{}
+			if err := conn.ReadJSON(&frame); err != nil || frame["action"] == "ACTION_COMPLETE" {
+				break
+			}
+		}
+		// audio keeps coming until the client hangs up
+		for {
+			if err := conn.WriteMessage(websocket.BinaryMessage, pcm(320)); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+	errCancelled := errors.New("client cancelled")
+	calls := 0
+	err := SynthesizeStream(0, testCredential, "你好", func(data []byte) error {
+		if calls++; calls == 3 {
+			return errCancelled
+		}
+		return nil
+	}, nil)
+	if !errors.Is(err, errCancelled) {
+		t.Fatalf("SynthesizeStream() = %v, want the send error %v", err, errCancelled)
+	}
+	if calls != 3 {
+		t.Errorf("send called %d times, want no call after the failing one", calls)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("the connection is still open after the send error")
+	}
+}
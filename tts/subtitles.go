@@ -0,0 +1,75 @@
+package tts
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// subtitleJSON is the element of SubtitlesToJSON, its fields are part of the output format
+type subtitleJSON struct {
+	Text       string `json:"text"`
+	BeginMs    int64  `json:"begin_ms"`
+	EndMs      int64  `json:"end_ms"`
+	BeginIndex int    `json:"begin_index"`
+	EndIndex   int    `json:"end_index"`
+	Phoneme    string `json:"phoneme,omitempty"`
+}
+
+// SubtitlesToJSON encodes subs as a JSON array for frontends, each subtitle as an object
+// with the keys text, begin_ms, end_ms, begin_index and end_index, in that order, plus
+// phoneme when not empty. No subtitles encode as [].
+func SubtitlesToJSON(subs []Synthesisv2Subtitle) ([]byte, error) {
+	out := make([]subtitleJSON, len(subs))
+	for i, sub := range subs {
+		out[i] = subtitleJSON{
+			Text:       sub.Text,
+			BeginMs:    sub.BeginTime,
+			EndMs:      sub.EndTime,
+			BeginIndex: sub.BeginIndex,
+			EndIndex:   sub.EndIndex,
+			Phoneme:    sub.Phoneme,
+		}
+	}
+	return json.Marshal(out)
+}
+
+// WordTiming is the time span of a word, or of a character for Chinese, in the audio
+type WordTiming struct {
+	Text       string
+	Phoneme    string // pinyin with tone number, when the voice reports it
+	BeginMs    int64
+	EndMs      int64
+	BeginIndex int // rune offset of the word in the text sent
+	EndIndex   int
+}
+
+// WordTimingsFromSubtitles returns the timings of subs in text order, skipping subtitles
+// without text. The server reports a subtitle per word or Chinese character, so no
+// splitting is needed; Phoneme is only set by the voices reporting pronunciations, mostly
+// the Chinese premium voices, the others leave it empty.
+func WordTimingsFromSubtitles(subs []Synthesisv2Subtitle) []WordTiming {
+	timings := make([]WordTiming, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Text == "" {
+			continue
+		}
+		timings = append(timings, WordTiming{
+			Text:       sub.Text,
+			Phoneme:    sub.Phoneme,
+			BeginMs:    sub.BeginTime,
+			EndMs:      sub.EndTime,
+			BeginIndex: sub.BeginIndex,
+			EndIndex:   sub.EndIndex,
+		})
+	}
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].BeginIndex < timings[j].BeginIndex
+	})
+	return timings
+}
+
+// WordTimings returns the timings of the words synthesized so far, for karaoke-style
+// highlighting. It needs EnableSubtitle and is empty with WithoutSubtitleAccumulation.
+func (synthesizer *SpeechWsv2Synthesizer) WordTimings() []WordTiming {
+	return WordTimingsFromSubtitles(synthesizer.Subtitles())
+}
@@ -0,0 +1,73 @@
+package tts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestSubtitlesToJSONGolden(t *testing.T) {
+	subs := []Synthesisv2Subtitle{
+		{Text: "你", Phoneme: "ni3", BeginTime: 0, EndTime: 160, BeginIndex: 0, EndIndex: 1},
+		{Text: "好", Phoneme: "hao3", BeginTime: 160, EndTime: 320, BeginIndex: 1, EndIndex: 2},
+		{Text: "hello", BeginTime: 320, EndTime: 700, BeginIndex: 2, EndIndex: 7},
+	}
+	got, err := SubtitlesToJSON(subs)
+	if err != nil {
+		t.Fatalf("SubtitlesToJSON() error = %v", err)
+	}
+	const path = "testdata/subtitles.golden.json"
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, append(got, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, bytes.TrimSpace(want)) {
+		t.Errorf("SubtitlesToJSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSubtitlesToJSONEmpty(t *testing.T) {
+	for _, subs := range [][]Synthesisv2Subtitle{nil, {}} {
+		got, err := SubtitlesToJSON(subs)
+		if err != nil || string(got) != "[]" {
+			t.Errorf("SubtitlesToJSON(%#v) = %s, %v, want []", subs, got, err)
+		}
+	}
+}
+
+func TestWordTimingsFromFrames(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{})
+	s.EnableSubtitle = true
+	startFake(s, conn)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"你","Phoneme":"ni3","BeginIndex":0,"EndIndex":1,"BeginTime":0,"EndTime":160}]}}`)
+	conn.text(`{"code":0,"message":"success","result":{"subtitles":[{"Text":"","BeginIndex":1,"EndIndex":1,"BeginTime":160,"EndTime":200},{"Text":"好","Phoneme":"hao3","BeginIndex":1,"EndIndex":2,"BeginTime":200,"EndTime":350}]}}`)
+	conn.final()
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []WordTiming{
+		{Text: "你", Phoneme: "ni3", BeginMs: 0, EndMs: 160, BeginIndex: 0, EndIndex: 1},
+		{Text: "好", Phoneme: "hao3", BeginMs: 200, EndMs: 350, BeginIndex: 1, EndIndex: 2},
+	}
+	if got := s.WordTimings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WordTimings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWordTimingsFromSubtitlesOrder(t *testing.T) {
+	subs := []Synthesisv2Subtitle{
+		{Text: "world", BeginIndex: 6, EndIndex: 11, BeginTime: 400, EndTime: 800},
+		{Text: "hello", BeginIndex: 0, EndIndex: 5, BeginTime: 0, EndTime: 400},
+	}
+	got := WordTimingsFromSubtitles(subs)
+	if len(got) != 2 || got[0].Text != "hello" || got[1].Text != "world" || got[1].Phoneme != "" {
+		t.Errorf("WordTimingsFromSubtitles() = %+v, want hello then world", got)
+	}
+}
@@ -0,0 +1,133 @@
+package tts
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextMode tells how text passed to Send is interpreted
+type TextMode int
+
+const (
+	// TextModeSSML sends the text untouched, this is the default for compatibility. Plain text
+	// containing '<' or '&' may then be misinterpreted by the server as SSML markup.
+	TextModeSSML TextMode = iota
+	// TextModePlain escapes XML special characters so the text is always read literally
+	TextModePlain
+)
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// EscapeText escapes the XML special characters of plain text
+func EscapeText(text string) string {
+	return xmlEscaper.Replace(text)
+}
+
+var xmlUnescaper = strings.NewReplacer(
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&quot;", `"`,
+	"&apos;", "'",
+)
+
+// stripTags removes the SSML tags of text and unescapes the XML entities, leaving the
+// characters read aloud
+func stripTags(text string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			b.WriteString(text)
+			break
+		}
+		b.WriteString(text[:start])
+		end := strings.IndexByte(text[start:], '>')
+		if end < 0 {
+			break
+		}
+		text = text[start+end+1:]
+	}
+	return xmlUnescaper.Replace(b.String())
+}
+
+// EstimateCost counts the billable characters of text, SSML tags excluded, and prices them
+// at pricePer1kChars per thousand characters. It is an estimate for budgeting only, the
+// amount billed follows the pricing rules of the service.
+func EstimateCost(text string, pricePer1kChars float64) (chars int, cost float64) {
+	chars = utf8.RuneCountInString(stripTags(text))
+	return chars, float64(chars) * pricePer1kChars / 1000
+}
+
+// Speaking rates and compressed bitrates of EstimateAudioBytes
+const (
+	estimatedCJKPerSecond    = 4.0  // Chinese characters, about 240 per minute
+	estimatedOtherPerSecond  = 14.0 // letters, digits and spaces, about 150 words per minute
+	estimatedMP3BytesPerSec  = 4000 // 32 kbps
+	estimatedOpusBytesPerSec = 2000 // 16 kbps
+)
+
+// EstimateAudioBytes estimates the size of the audio of text at sampleRate in codec, to
+// pre-allocate a buffer, e.g. make([]byte, 0, EstimateAudioBytes(text, 16000, "pcm")). It
+// is approximate: the duration assumes an average speaking rate at Speed 0, SSML tags
+// excluded, and mp3 and opus an average bitrate. Unknown codecs are estimated as pcm, the
+// largest.
+func EstimateAudioBytes(text string, sampleRate int64, codec string) int {
+	var seconds float64
+	for _, r := range stripTags(text) {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+			unicode.Is(unicode.Hangul, r) {
+			seconds += 1 / estimatedCJKPerSecond
+		} else if !unicode.IsControl(r) {
+			seconds += 1 / estimatedOtherPerSecond
+		}
+	}
+	switch strings.ToLower(codec) {
+	case "mp3":
+		return int(seconds * estimatedMP3BytesPerSec)
+	case "opus":
+		return int(seconds * estimatedOpusBytesPerSec)
+	default:
+		return int(seconds * float64(sampleRate*2))
+	}
+}
+
+// sentenceEnds are the punctuation marks closing a sentence for WithFlushOnPunctuation
+const sentenceEnds = "。！？；!?;\n"
+
+// sentenceClosers may follow the end of a sentence and stay with it
+const sentenceClosers = "”’」』）)\"'"
+
+// splitSentences cuts text after each sentence ending punctuation mark, along with the
+// quotes or brackets following it. The text after the last one is returned as is.
+func splitSentences(text string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !strings.ContainsRune(sentenceEnds, r) {
+			continue
+		}
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !strings.ContainsRune(sentenceClosers, r) && !strings.ContainsRune(sentenceEnds, r) {
+				break
+			}
+			i += size
+		}
+		parts = append(parts, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		parts = append(parts, text[start:])
+	}
+	return parts
+}
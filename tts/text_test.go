@@ -0,0 +1,107 @@
+package tts
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantChars int
+	}{
+		{"plain", "你好，世界", 5},
+		{"latin", "hello world", 11},
+		{"ssml tags excluded", `<speak>你好<break time="500ms"/>世界</speak>`, 4},
+		{"entities counted once", "<speak>Tom &amp; Jerry</speak>", 11},
+		{"empty", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chars, cost := EstimateCost(tt.text, 2.0)
+			if chars != tt.wantChars {
+				t.Errorf("EstimateCost(%q) chars = %d, want %d", tt.text, chars, tt.wantChars)
+			}
+			if want := float64(tt.wantChars) * 2.0 / 1000; math.Abs(cost-want) > 1e-12 {
+				t.Errorf("EstimateCost(%q) cost = %g, want %g", tt.text, cost, want)
+			}
+		})
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"你好。今天天气很好！", []string{"你好。", "今天天气很好！"}},
+		{"他说：“走吧！”然后", []string{"他说：“走吧！”", "然后"}},
+		{"Really?! Yes.", []string{"Really?!", " Yes."}},
+		{"no end", []string{"no end"}},
+	}
+	for _, tt := range tests {
+		if got := splitSentences(tt.text); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFlushOnPunctuationSplitsSends(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithFlushOnPunctuation())
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Send("第一句。第二句！第三"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var got []string
+	for _, frame := range conn.sent() {
+		got = append(got, frame["data"].(string))
+	}
+	if want := []string{"第一句。", "第二句！", "第三"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("frames sent %q, want %q", got, want)
+	}
+}
+
+func TestEstimateAudioBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		rate  int64
+		codec string
+		want  int
+	}{
+		{"empty", "", 16000, "pcm", 0},
+		{"chinese pcm", "你好世界", 16000, "pcm", 32000},
+		{"chinese pcm 8k", "你好世界", 8000, "pcm", 16000},
+		{"chinese mp3", "你好世界", 16000, "mp3", 4000},
+		{"chinese opus", "你好世界", 16000, "opus", 2000},
+		{"latin pcm", "hello world 14", 16000, "pcm", 32000},
+		{"tags excluded", `<speak>你好<break time="1s"/>世界</speak>`, 16000, "pcm", 32000},
+		{"unknown codec as pcm", "你好世界", 16000, "flac", 32000},
+		{"codec case", "你好世界", 16000, "MP3", 4000},
+	}
+	for _, tt := range tests {
+		// the seconds are summed as floats, then truncated
+		if got := EstimateAudioBytes(tt.text, tt.rate, tt.codec); got < tt.want-1 || got > tt.want {
+			t.Errorf("%s: EstimateAudioBytes() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateAudioBytesScales(t *testing.T) {
+	text := "今天天气很好，我们去公园散步吧。"
+	for _, codec := range []string{"pcm", "mp3", "opus"} {
+		once := EstimateAudioBytes(text, 16000, codec)
+		twice := EstimateAudioBytes(strings.Repeat(text, 2), 16000, codec)
+		if once <= 0 || math.Abs(float64(twice-2*once)) > 1 {
+			t.Errorf("%s: EstimateAudioBytes() = %d for the text, %d for it twice, want double", codec, once, twice)
+		}
+	}
+	if pcm, mp3, opus := EstimateAudioBytes(text, 16000, "pcm"), EstimateAudioBytes(text, 16000, "mp3"),
+		EstimateAudioBytes(text, 16000, "opus"); !(pcm > mp3 && mp3 > opus) {
+		t.Errorf("EstimateAudioBytes() = %d pcm, %d mp3, %d opus, want decreasing", pcm, mp3, opus)
+	}
+}
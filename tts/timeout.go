@@ -0,0 +1,77 @@
+package tts
+
+import "time"
+
+// AdaptiveTimeout derives the drain timeout from the amount of text sent, long texts
+// legitimately take longer to synthesize
+type AdaptiveTimeout struct {
+	CharsPerSecond float64       // estimated synthesis throughput
+	Floor          time.Duration // lower bound of the timeout
+	Ceiling        time.Duration // upper bound of the timeout, zero means unbounded
+}
+
+// Timeout returns the drain timeout for chars characters
+func (a AdaptiveTimeout) Timeout(chars int) time.Duration {
+	var d time.Duration
+	if a.CharsPerSecond > 0 {
+		d = time.Duration(float64(chars) / a.CharsPerSecond * float64(time.Second))
+	}
+	if d < a.Floor {
+		d = a.Floor
+	}
+	if a.Ceiling > 0 && d > a.Ceiling {
+		d = a.Ceiling
+	}
+	return d
+}
+
+// drainTimeout is how long to wait for the final frame after Complete, zero means forever
+func (synthesizer *SpeechWsv2Synthesizer) drainTimeout() time.Duration {
+	if synthesizer.adaptiveTimeout != nil {
+		return synthesizer.adaptiveTimeout.Timeout(int(synthesizer.sentChars()))
+	}
+	return synthesizer.DrainTimeout
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) startDrainTimer() {
+	d := synthesizer.drainTimeout()
+	if d <= 0 {
+		return
+	}
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	if synthesizer.terminated || synthesizer.drainTimer != nil {
+		return
+	}
+	synthesizer.drainTimer = time.AfterFunc(d, func() {
+		synthesizer.shutdown(ErrDrainTimeout)
+	})
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) stopDrainTimer() {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	if synthesizer.drainTimer != nil {
+		synthesizer.drainTimer.Stop()
+	}
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) startSessionTimer() {
+	d := synthesizer.MaxSessionDuration
+	if d <= 0 {
+		return
+	}
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	synthesizer.sessionTimer = time.AfterFunc(d, func() {
+		synthesizer.shutdown(ErrSessionDuration)
+	})
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) stopSessionTimer() {
+	synthesizer.statusMutex.Lock()
+	defer synthesizer.statusMutex.Unlock()
+	if synthesizer.sessionTimer != nil {
+		synthesizer.sessionTimer.Stop()
+	}
+}
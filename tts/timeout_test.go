@@ -0,0 +1,113 @@
+package tts
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	a := AdaptiveTimeout{CharsPerSecond: 10, Floor: 2 * time.Second, Ceiling: time.Minute}
+	tests := []struct {
+		chars int
+		want  time.Duration
+	}{
+		{0, 2 * time.Second},
+		{10, 2 * time.Second},
+		{150, 15 * time.Second},
+		{1000, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := a.Timeout(tt.chars); got != tt.want {
+			t.Errorf("Timeout(%d) = %v, want %v", tt.chars, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptiveTimeoutFromTextSent(t *testing.T) {
+	conn := newFakeConn()
+	s := NewSpeechWsv2Synthesizer(0, nil, &recordListener{}, WithAdaptiveTimeout(20, time.Second, 0))
+	s.DrainTimeout = time.Hour
+	startFake(s, conn)
+	defer s.Abort()
+	if err := s.Send(strings.Repeat("你", 300)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got, want := s.drainTimeout(), 15*time.Second; got != want {
+		t.Errorf("drainTimeout() = %v after 300 characters at 20 per second, want %v", got, want)
+	}
+}
+
+func TestMaxSessionDurationCapsSlowSession(t *testing.T) {
+	const max = 100 * time.Millisecond
+	mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		// audio trickling in, the final frame never comes
+		for {
+			if err := conn.WriteMessage(websocket.BinaryMessage, pcm(320)); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, listener)
+	s.MaxSessionDuration = max
+	start := time.Now()
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	err := s.Wait()
+	if !errors.Is(err, ErrSessionDuration) {
+		t.Fatalf("Wait() = %v, want %v", err, ErrSessionDuration)
+	}
+	if elapsed := time.Since(start); elapsed < max || elapsed > max+time.Second {
+		t.Errorf("session capped after %v, want about %v", elapsed, max)
+	}
+	if listener.count("audio") == 0 {
+		t.Error("no audio delivered before the cap")
+	}
+	if failures := listener.failures(); len(failures) != 0 {
+		t.Errorf("OnSynthesisFail called with %v, want a graceful close", failures)
+	}
+}
+
+func TestMaxAudioBytes(t *testing.T) {
+	var out bytes.Buffer
+	conn := newFakeConn()
+	listener := &recordListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	s.Codec = "pcm"
+	s.MaxAudioBytes = 500
+	s.AudioWriter = &out
+	s.Transcoder = NewPCMToWAVTranscoder(16000)
+	startFake(s, conn)
+	for i := 0; i < 3; i++ {
+		conn.binary(pcm(320))
+	}
+	conn.final()
+	if err := s.Wait(); !errors.Is(err, ErrAudioLimitReached) {
+		t.Fatalf("Wait() = %v, want %v", err, ErrAudioLimitReached)
+	}
+	// closed as Close does: neither OnSynthesisEnd nor OnSynthesisFail
+	want := []string{"start", "audio 320", "audio 180"}
+	if got := listener.eventList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	// the output is finalized: flushing wrote the WAV header of the audio kept
+	wantOut := append(WAVHeader(16000, 500), pcm(320)...)
+	wantOut = append(wantOut, pcm(320)[:180]...)
+	if !bytes.Equal(out.Bytes(), wantOut) {
+		t.Errorf("AudioWriter got %d bytes, want the WAV header and the first 500 bytes", out.Len())
+	}
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.closes == 0 {
+		t.Error("connection left open after the limit")
+	}
+}
@@ -0,0 +1,104 @@
+package tts
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+// SynthesizeToFile synthesizes text into outPath and returns once the synthesis ended.
+// The extension of outPath selects the format:
+//
+//	.wav   pcm wrapped in a WAV header
+//	.pcm   raw 16-bit mono pcm
+//	.mp3   mp3, written as received
+//	.opus  opus, written as received
+//
+// The Codec set by opts is overridden accordingly, text longer than MaxChunkChars is split.
+// outPath is removed when the synthesis fails.
+func SynthesizeToFile(appID int64, credential *common.Credential, text, outPath string, opts ...Option) error {
+	codec, wav, err := codecForPath(outPath)
+	if err != nil {
+		return err
+	}
+	listener := &failureListener{}
+	synthesizer := NewSpeechWsv2Synthesizer(appID, credential, listener, append([]Option{WithAutoSplit()}, opts...)...)
+	synthesizer.Codec = codec
+	if wav {
+		synthesizer.Transcoder = NewPCMToWAVTranscoder(synthesizer.SampleRate)
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	synthesizer.AudioWriter = w
+
+	err = synthesizeAll(synthesizer, text)
+	if err == nil {
+		err = listener.failure()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outPath)
+	}
+	return err
+}
+
+func synthesizeAll(synthesizer *SpeechWsv2Synthesizer, text string) error {
+	if err := synthesizer.Prepare(); err != nil {
+		return err
+	}
+	err := synthesizer.Send(text)
+	if err == nil {
+		err = synthesizer.Complete()
+	}
+	if err != nil {
+		// a write fails once the session was stopped, e.g. by a listener error Wait returns
+		synthesizer.Abort()
+		if werr := synthesizer.Wait(); werr != nil {
+			return werr
+		}
+		return err
+	}
+	return synthesizer.Wait()
+}
+
+func codecForPath(path string) (codec string, wav bool, err error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".wav":
+		return "pcm", true, nil
+	case ".pcm", ".mp3", ".opus":
+		return ext[1:], false, nil
+	default:
+		return "", false, fmt.Errorf("unsupported output extension %q, want .wav, .pcm, .mp3 or .opus", ext)
+	}
+}
+
+// failureListener keeps the first failure reported to OnSynthesisFail
+type failureListener struct {
+	NoopListener
+	mutex sync.Mutex
+	err   error
+}
+
+func (l *failureListener) OnSynthesisFail(r *SpeechWsv2SynthesisResponse, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+func (l *failureListener) failure() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.err
+}
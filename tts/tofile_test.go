@@ -0,0 +1,71 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSynthesizeToFileWAV(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, pcm(320), pcm(320))
+	})
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if err := SynthesizeToFile(0, testCredential, "你好", path); err != nil {
+		t.Fatalf("SynthesizeToFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 44+640 {
+		t.Fatalf("%s is %d bytes, want a 44 bytes header and 640 bytes of pcm", path, len(data))
+	}
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Errorf("%s starts with %q, want a RIFF WAVE header", path, data[:44])
+	}
+	if n := binary.LittleEndian.Uint32(data[40:44]); n != 640 {
+		t.Errorf("WAV data length = %d, want 640", n)
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:28]); rate != 16000 {
+		t.Errorf("WAV sample rate = %d, want 16000", rate)
+	}
+	if codec := server.requests()[0].URL.Query().Get("Codec"); codec != "pcm" {
+		t.Errorf("requested Codec %q, want pcm", codec)
+	}
+}
+
+func TestSynthesizeToFileMP3(t *testing.T) {
+	mp3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), 0xff, 0xfb, 0x90, 0x64)
+	server := mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, mp3)
+	})
+	path := filepath.Join(t.TempDir(), "out.mp3")
+	if err := SynthesizeToFile(0, testCredential, "你好", path); err != nil {
+		t.Fatalf("SynthesizeToFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, mp3) {
+		t.Errorf("%s = %q, want the mp3 as received", path, data)
+	}
+	if codec := server.requests()[0].URL.Query().Get("Codec"); codec != "mp3" {
+		t.Errorf("requested Codec %q, want mp3", codec)
+	}
+}
+
+func TestSynthesizeToFileUnsupportedExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.flac")
+	if err := SynthesizeToFile(0, testCredential, "你好", path); err == nil {
+		t.Error("SynthesizeToFile() to .flac error = nil")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s was created", path)
+	}
+}
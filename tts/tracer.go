@@ -0,0 +1,78 @@
+package tts
+
+import "sync/atomic"
+
+// Tracer creates the spans of WithTracer, adapt it to a tracing library such as
+// OpenTelemetry, which this package doesn't depend on:
+//
+//	type otelTracer struct {
+//		ctx    context.Context
+//		tracer trace.Tracer
+//	}
+//
+//	func (t otelTracer) Start(name string) tts.Span {
+//		_, span := t.tracer.Start(t.ctx, name)
+//		return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.span.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	func (s otelSpan) AddEvent(name string) { s.span.AddEvent(name) }
+//
+//	func (s otelSpan) End(err error) {
+//		if err != nil {
+//			s.span.RecordError(err)
+//			s.span.SetStatus(codes.Error, err.Error())
+//		}
+//		s.span.End()
+//	}
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is a span created by a Tracer, its methods are called from the session goroutines
+type Span interface {
+	SetAttribute(key string, value interface{})
+	AddEvent(name string)
+	// End ends the span, err is the failure of the session or nil
+	End(err error)
+}
+
+// spanName is the name of the span covering a session
+const spanName = "tts.synthesis"
+
+// startSpan starts the span of the session, if a Tracer is set and it isn't started yet
+func (synthesizer *SpeechWsv2Synthesizer) startSpan() {
+	if synthesizer.tracer != nil && synthesizer.span == nil {
+		synthesizer.span = synthesizer.tracer.Start(spanName)
+	}
+}
+
+func (synthesizer *SpeechWsv2Synthesizer) spanEvent(name string) {
+	if synthesizer.span != nil {
+		synthesizer.span.AddEvent(name)
+	}
+}
+
+// endSpan sets the attributes of the session and ends its span with err. The span is
+// cleared, so that the next Prepare, e.g. the retry of a failed one, starts its own.
+func (synthesizer *SpeechWsv2Synthesizer) endSpan(err error) {
+	span := synthesizer.span
+	if span == nil {
+		return
+	}
+	span.SetAttribute("session_id", synthesizer.SessionId)
+	span.SetAttribute("request_id", synthesizer.Session().RequestId)
+	span.SetAttribute("voice_type", synthesizer.VoiceType)
+	span.SetAttribute("codec", synthesizer.Codec)
+	span.SetAttribute("bytes", atomic.LoadInt64(&synthesizer.counters.audioBytes))
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+	}
+	span.End(err)
+	synthesizer.span = nil
+}
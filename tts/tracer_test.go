@@ -0,0 +1,146 @@
+package tts
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordingTracer records the spans it creates
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	mutex  sync.Mutex
+	name   string
+	attrs  map[string]interface{}
+	events []string
+	ended  int
+	err    error
+}
+
+func (t *recordingTracer) Start(name string) Span {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	span := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func (t *recordingTracer) recorded() []*recordedSpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*recordedSpan(nil), t.spans...)
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *recordedSpan) AddEvent(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, name)
+}
+
+func (s *recordedSpan) End(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ended++
+	s.err = err
+}
+
+func TestTracerSpan(t *testing.T) {
+	mockServer(t, func(conn *websocket.Conn) {
+		synthesize(conn, pcm(320), pcm(160))
+	})
+	tracer := &recordingTracer{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithTracer(tracer))
+	s.VoiceType = 101001
+	s.Codec = "pcm"
+	if err := s.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if err := s.Send("你好"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	spans := tracer.recorded()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans started, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.name != spanName || span.ended != 1 || span.err != nil {
+		t.Errorf("span %q ended %d times with %v, want %q ended once without error", span.name, span.ended, span.err, spanName)
+	}
+	if want := []string{"start", "first_audio", "end"}; !reflect.DeepEqual(span.events, want) {
+		t.Errorf("span events = %q, want %q", span.events, want)
+	}
+	want := map[string]interface{}{
+		"session_id": s.SessionId,
+		"request_id": "test-request",
+		"voice_type": int64(101001),
+		"codec":      "pcm",
+		"bytes":      int64(480),
+	}
+	if !reflect.DeepEqual(span.attrs, want) {
+		t.Errorf("span attributes = %v, want %v", span.attrs, want)
+	}
+}
+
+func TestTracerSpanPerPrepareAttempt(t *testing.T) {
+	var attempts int32
+	mockServer(t, func(conn *websocket.Conn) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"code":5000,"message":"internal error"}`))
+			drain(conn)
+			return
+		}
+		synthesize(conn, pcm(320))
+	})
+	tracer := &recordingTracer{}
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{},
+		WithTracer(tracer), WithBackoff(ConstantBackoff{Delay: time.Millisecond}))
+	if err := s.PrepareWithRetry(context.Background(), 3); err != nil {
+		t.Fatalf("PrepareWithRetry() error = %v", err)
+	}
+	if err := s.Send("你好"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	spans := tracer.recorded()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans started, want one per attempt", len(spans))
+	}
+	failed, session := spans[0], spans[1]
+	if failed.ended != 1 || failed.err == nil || failed.attrs["error"] != failed.err.Error() {
+		t.Errorf("failed attempt span ended %d times with %v, attributes %v, want its error", failed.ended, failed.err, failed.attrs)
+	}
+	if session.ended != 1 || session.err != nil || session.attrs["bytes"] != int64(320) {
+		t.Errorf("session span ended %d times with %v, attributes %v, want the session", session.ended, session.err, session.attrs)
+	}
+	if want := []string{"start", "first_audio", "end"}; !reflect.DeepEqual(session.events, want) {
+		t.Errorf("session span events = %q, want %q", session.events, want)
+	}
+}
@@ -0,0 +1,159 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Transcoder converts the audio received from the server before it is written
+// to the AudioWriter. Write returns the transcoded bytes available so far,
+// Flush returns whatever is still buffered once the synthesis ends.
+//
+// Transcoders only apply when the source Codec is pcm, audio of other codecs
+// is written to the AudioWriter untouched.
+type Transcoder interface {
+	Write(pcm []byte) ([]byte, error)
+	Flush() ([]byte, error)
+}
+
+// PCMToWAVTranscoder wraps 16-bit PCM into a WAV container. The RIFF header
+// carries the total data length, so the audio is buffered and emitted on Flush.
+type PCMToWAVTranscoder struct {
+	SampleRate int64
+	Channels   int // 2 after a MonoToStereo, zero means mono
+	buf        bytes.Buffer
+}
+
+// NewPCMToWAVTranscoder creates instance of PCMToWAVTranscoder
+func NewPCMToWAVTranscoder(sampleRate int64) *PCMToWAVTranscoder {
+	return &PCMToWAVTranscoder{SampleRate: sampleRate}
+}
+
+// Write buffers pcm, nothing is returned until Flush
+func (t *PCMToWAVTranscoder) Write(pcm []byte) ([]byte, error) {
+	t.buf.Write(pcm)
+	return nil, nil
+}
+
+// Flush returns the WAV header followed by the buffered pcm
+func (t *PCMToWAVTranscoder) Flush() ([]byte, error) {
+	channels := t.Channels
+	if channels <= 0 {
+		channels = 1
+	}
+	out := append(wavHeader(t.SampleRate, channels, 16, t.buf.Len()), t.buf.Bytes()...)
+	t.buf.Reset()
+	return out, nil
+}
+
+// WAVHeader returns the 44 bytes RIFF header of a 16-bit mono PCM WAV file
+// holding dataLen bytes of audio.
+func WAVHeader(sampleRate int64, dataLen int) []byte {
+	return wavHeader(sampleRate, 1, 16, dataLen)
+}
+
+// WAVHeaderFormat returns the 44 bytes RIFF header of a PCM WAV file with the given channel
+// count and sample size holding dataLen bytes of audio, e.g. 2 and 16 after a MonoToStereo.
+// channels must be 1 to 8 and bitsPerSample 8, 16, 24 or 32, dataLen a whole number of
+// frames.
+func WAVHeaderFormat(sampleRate int64, channels, bitsPerSample, dataLen int) ([]byte, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("WAVHeaderFormat: invalid sample rate %d", sampleRate)
+	}
+	if channels < 1 || channels > 8 {
+		return nil, fmt.Errorf("WAVHeaderFormat: invalid channel count %d", channels)
+	}
+	switch bitsPerSample {
+	case 8, 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("WAVHeaderFormat: invalid bits per sample %d", bitsPerSample)
+	}
+	if blockAlign := channels * bitsPerSample / 8; dataLen < 0 || dataLen%blockAlign != 0 {
+		return nil, fmt.Errorf("WAVHeaderFormat: data length %d not a multiple of the %d bytes frame", dataLen, blockAlign)
+	}
+	return wavHeader(sampleRate, channels, bitsPerSample, dataLen), nil
+}
+
+func wavHeader(sampleRate int64, channels, bitsPerSample, dataLen int) []byte {
+	blockAlign := channels * bitsPerSample / 8
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+dataLen))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate)*uint32(blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], uint16(bitsPerSample))
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataLen))
+	return header
+}
+
+// MonoToStereo is a Transcoder duplicating each sample of 16-bit mono pcm on two channels,
+// for players requiring stereo. Follow it with a PCMToWAVTranscoder whose Channels is 2
+// to write a WAV file, see ChainTranscoders.
+type MonoToStereo struct {
+	odd []byte // last byte of an odd length Write
+}
+
+// Write returns the stereo pcm, twice the length of the mono pcm
+func (t *MonoToStereo) Write(pcm []byte) ([]byte, error) {
+	data := append(t.odd, pcm...)
+	t.odd = nil
+	if len(data)%2 == 1 {
+		t.odd = []byte{data[len(data)-1]}
+		data = data[:len(data)-1]
+	}
+	out := make([]byte, 0, 2*len(data))
+	for i := 0; i < len(data); i += 2 {
+		out = append(out, data[i], data[i+1], data[i], data[i+1])
+	}
+	return out, nil
+}
+
+// Flush drops a pending half sample
+func (t *MonoToStereo) Flush() ([]byte, error) {
+	t.odd = nil
+	return nil, nil
+}
+
+// transcoderChain is returned by ChainTranscoders
+type transcoderChain []Transcoder
+
+// ChainTranscoders returns a Transcoder passing the audio through stages in order, e.g.
+// a Resampler, a MonoToStereo then a PCMToWAVTranscoder
+func ChainTranscoders(stages ...Transcoder) Transcoder {
+	return transcoderChain(stages)
+}
+
+func (c transcoderChain) Write(pcm []byte) ([]byte, error) {
+	var err error
+	for _, stage := range c {
+		if pcm, err = stage.Write(pcm); err != nil {
+			return nil, err
+		}
+	}
+	return pcm, nil
+}
+
+// Flush flushes each stage in order, passing what a stage flushes through the next ones
+func (c transcoderChain) Flush() ([]byte, error) {
+	var data []byte
+	for _, stage := range c {
+		out, err := stage.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		flushed, err := stage.Flush()
+		if err != nil {
+			return nil, err
+		}
+		data = append(out, flushed...)
+	}
+	return data, nil
+}
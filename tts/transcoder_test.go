@@ -0,0 +1,164 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestPCMToWAVTranscoder(t *testing.T) {
+	tc := NewPCMToWAVTranscoder(16000)
+	for _, chunk := range [][]byte{pcm(100), pcm(60)} {
+		out, err := tc.Write(chunk)
+		if err != nil || len(out) != 0 {
+			t.Fatalf("Write() = %d bytes, %v, want nothing before Flush", len(out), err)
+		}
+	}
+	out, err := tc.Flush()
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(out) != 44+160 {
+		t.Fatalf("Flush() = %d bytes, want %d", len(out), 44+160)
+	}
+	header := out[:44]
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" || string(header[12:16]) != "fmt " || string(header[36:40]) != "data" {
+		t.Errorf("header chunks = %q, want RIFF/WAVE/fmt /data", header)
+	}
+	fields := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"riff size", binary.LittleEndian.Uint32(header[4:]), 36 + 160},
+		{"format", uint32(binary.LittleEndian.Uint16(header[20:])), 1},
+		{"channels", uint32(binary.LittleEndian.Uint16(header[22:])), 1},
+		{"sample rate", binary.LittleEndian.Uint32(header[24:]), 16000},
+		{"byte rate", binary.LittleEndian.Uint32(header[28:]), 32000},
+		{"block align", uint32(binary.LittleEndian.Uint16(header[32:])), 2},
+		{"bits per sample", uint32(binary.LittleEndian.Uint16(header[34:])), 16},
+		{"data size", binary.LittleEndian.Uint32(header[40:]), 160},
+	}
+	for _, f := range fields {
+		if f.got != f.want {
+			t.Errorf("%s = %d, want %d", f.name, f.got, f.want)
+		}
+	}
+	if want := append(pcm(100), pcm(60)...); !bytes.Equal(out[44:], want) {
+		t.Errorf("data differs from the pcm written")
+	}
+	if !bytes.Equal(header, WAVHeader(16000, 160)) {
+		t.Errorf("header differs from WAVHeader(16000, 160)")
+	}
+}
+
+func TestMonoToStereo(t *testing.T) {
+	mono := pcm(160)
+	tc := &MonoToStereo{}
+	var out []byte
+	// a write ending mid sample
+	for _, chunk := range [][]byte{mono[:51], mono[51:]} {
+		data, err := tc.Write(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, data...)
+	}
+	if len(out) != 2*len(mono) {
+		t.Fatalf("%d bytes of stereo, want %d", len(out), 2*len(mono))
+	}
+	for i := 0; i < len(mono); i += 2 {
+		left, right := out[2*i:2*i+2], out[2*i+2:2*i+4]
+		if !bytes.Equal(left, mono[i:i+2]) || !bytes.Equal(right, mono[i:i+2]) {
+			t.Fatalf("frame %d = % x % x, want sample % x on both channels", i/2, left, right, mono[i:i+2])
+		}
+	}
+}
+
+func TestMonoToStereoWAV(t *testing.T) {
+	wav := &PCMToWAVTranscoder{SampleRate: 16000, Channels: 2}
+	tc := ChainTranscoders(&MonoToStereo{}, wav)
+	if _, err := tc.Write(pcm(320)); err != nil {
+		t.Fatal(err)
+	}
+	out, err := tc.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 44+640 {
+		t.Fatalf("Flush() = %d bytes, want %d", len(out), 44+640)
+	}
+	want, err := WAVHeaderFormat(16000, 2, 16, 640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out[:44], want) {
+		t.Errorf("header = % x, want % x", out[:44], want)
+	}
+	if channels, byteRate := binary.LittleEndian.Uint16(out[22:]), binary.LittleEndian.Uint32(out[28:]); channels != 2 || byteRate != 64000 {
+		t.Errorf("header has %d channels and %d bytes per second, want 2 and 64000", channels, byteRate)
+	}
+}
+
+func TestWAVHeaderFormat(t *testing.T) {
+	tests := []struct {
+		name                    string
+		rate                    int64
+		channels, bits, dataLen int
+		byteRate, blockAlign    int
+	}{
+		{"mono/16", 16000, 1, 16, 320, 32000, 2},
+		{"stereo/16", 16000, 2, 16, 640, 64000, 4},
+		{"mono/8", 8000, 1, 8, 160, 8000, 1},
+	}
+	for _, tt := range tests {
+		header, err := WAVHeaderFormat(tt.rate, tt.channels, tt.bits, tt.dataLen)
+		if err != nil {
+			t.Fatalf("%s: WAVHeaderFormat() error = %v", tt.name, err)
+		}
+		if len(header) != 44 || string(header[0:4]) != "RIFF" || string(header[8:16]) != "WAVEfmt " || string(header[36:40]) != "data" {
+			t.Fatalf("%s: header %q isn't a RIFF WAVE header", tt.name, header)
+		}
+		fields := []struct {
+			name      string
+			got, want int
+		}{
+			{"RIFF size", int(binary.LittleEndian.Uint32(header[4:])), 36 + tt.dataLen},
+			{"format", int(binary.LittleEndian.Uint16(header[20:])), 1},
+			{"channels", int(binary.LittleEndian.Uint16(header[22:])), tt.channels},
+			{"sample rate", int(binary.LittleEndian.Uint32(header[24:])), int(tt.rate)},
+			{"byte rate", int(binary.LittleEndian.Uint32(header[28:])), tt.byteRate},
+			{"block align", int(binary.LittleEndian.Uint16(header[32:])), tt.blockAlign},
+			{"bits per sample", int(binary.LittleEndian.Uint16(header[34:])), tt.bits},
+			{"data size", int(binary.LittleEndian.Uint32(header[40:])), tt.dataLen},
+		}
+		for _, f := range fields {
+			if f.got != f.want {
+				t.Errorf("%s: %s = %d, want %d", tt.name, f.name, f.got, f.want)
+			}
+		}
+	}
+	if mono, _ := WAVHeaderFormat(16000, 1, 16, 320); !bytes.Equal(mono, WAVHeader(16000, 320)) {
+		t.Error("WAVHeaderFormat(mono/16) differs from WAVHeader")
+	}
+}
+
+func TestWAVHeaderFormatInvalid(t *testing.T) {
+	tests := []struct {
+		name                    string
+		rate                    int64
+		channels, bits, dataLen int
+	}{
+		{"zero rate", 0, 1, 16, 0},
+		{"no channel", 16000, 0, 16, 0},
+		{"too many channels", 16000, 9, 16, 0},
+		{"12 bits", 16000, 1, 12, 0},
+		{"partial frame", 16000, 2, 16, 6},
+		{"negative length", 16000, 1, 16, -2},
+	}
+	for _, tt := range tests {
+		if _, err := WAVHeaderFormat(tt.rate, tt.channels, tt.bits, tt.dataLen); err == nil {
+			t.Errorf("%s: WAVHeaderFormat() error = nil, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tts
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// transcriptListener records the callbacks of a session as a transcript, one line per
+// callback in call order: the callback kind followed by its size, the bytes of audio or the
+// number of subtitles of a text result. Failures are recorded with their error, which
+// makes transcripts of failed sessions depend on the error text.
+type transcriptListener struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *transcriptListener) record(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *transcriptListener) OnSynthesisStart(*SpeechWsv2SynthesisResponse) {
+	l.record("start")
+}
+
+func (l *transcriptListener) OnSynthesisEnd(r *SpeechWsv2SynthesisResponse) {
+	l.record("end %d", len(r.Result.Subtitles))
+}
+
+func (l *transcriptListener) OnAudioResult(data []byte) {
+	l.record("audio %d", len(data))
+}
+
+func (l *transcriptListener) OnTextResult(r *SpeechWsv2SynthesisResponse) {
+	l.record("text %d", len(r.Result.Subtitles))
+}
+
+func (l *transcriptListener) OnSynthesisFail(r *SpeechWsv2SynthesisResponse, err error) {
+	l.record("fail %s", err.Error())
+}
+
+// transcript returns the lines recorded so far, newline terminated
+func (l *transcriptListener) transcript() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.lines) == 0 {
+		return ""
+	}
+	return strings.Join(l.lines, "\n") + "\n"
+}
+
+// compareGolden compares the transcript with the golden file at path, the error names the
+// first differing line. With -update the golden file is rewritten instead.
+func (l *transcriptListener) compareGolden(path string) error {
+	if *updateGolden {
+		return ioutil.WriteFile(path, []byte(l.transcript()), 0644)
+	}
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	want := strings.Split(strings.TrimSuffix(strings.Replace(string(golden), "\r\n", "\n", -1), "\n"), "\n")
+	got := strings.Split(strings.TrimSuffix(l.transcript(), "\n"), "\n")
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			return fmt.Errorf("%s:%d: got %q, want %q", path, i+1, g, w)
+		}
+	}
+	return nil
+}
+
+func TestReplayMatchesGolden(t *testing.T) {
+	listener := &transcriptListener{}
+	s := NewSpeechWsv2Synthesizer(0, nil, listener)
+	if err := s.ReplayFrames("testdata/session.frames"); err != nil {
+		t.Fatalf("ReplayFrames() error = %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if err := listener.compareGolden("testdata/session.golden"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompareGoldenReportsFirstDifference(t *testing.T) {
+	if *updateGolden {
+		t.Skip("would overwrite the golden file")
+	}
+	listener := &transcriptListener{}
+	listener.OnSynthesisStart(nil)
+	listener.OnAudioResult(pcm(100))
+	err := listener.compareGolden("testdata/session.golden")
+	if err == nil || !strings.Contains(err.Error(), `session.golden:2: got "audio 100", want "text 1"`) {
+		t.Errorf("compareGolden() error = %v, want the second line reported", err)
+	}
+}
@@ -0,0 +1,80 @@
+package tts
+
+// VoiceInfo describes a VoiceType accepted by the websocket synthesizers
+type VoiceInfo struct {
+	VoiceType   int64
+	Name        string
+	Codecs      []string
+	Emotions    []string
+	SampleRates []int64
+}
+
+var (
+	defaultVoiceCodecs = []string{"pcm", "mp3"}
+	defaultSampleRates = []int64{8000, 16000}
+	defaultEmotions    = emotionCategories
+)
+
+// voiceCatalog mirrors the public voice list of the TTS service, voices released
+// afterwards can be used with WithoutVoiceValidation
+var voiceCatalog = []VoiceInfo{
+	{VoiceType: 0, Name: "云小宁", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1, Name: "云小奇", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 2, Name: "云小晚", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 4, Name: "云小叶", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 5, Name: "云小欣", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 6, Name: "云小龙", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 7, Name: "云小曼", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1000, Name: "智侠", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1001, Name: "智瑜", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1002, Name: "智聆", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1003, Name: "智美", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1050, Name: "WeJack", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 1051, Name: "WeRose", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101001, Name: "智瑜", Codecs: defaultVoiceCodecs, Emotions: defaultEmotions, SampleRates: defaultSampleRates},
+	{VoiceType: 101002, Name: "智聆", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101003, Name: "智美", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101004, Name: "智云", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101005, Name: "智莉", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101006, Name: "智言", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101007, Name: "智娜", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101008, Name: "智琪", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101009, Name: "智芸", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101010, Name: "智华", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101011, Name: "智燕", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101012, Name: "智丹", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101013, Name: "智辉", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101014, Name: "智宁", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101015, Name: "智萌", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101016, Name: "智甜", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101017, Name: "智蓉", Codecs: defaultVoiceCodecs, Emotions: defaultEmotions, SampleRates: defaultSampleRates},
+	{VoiceType: 101018, Name: "智靖", Codecs: defaultVoiceCodecs, Emotions: defaultEmotions, SampleRates: defaultSampleRates},
+	{VoiceType: 101019, Name: "智彤", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101020, Name: "智刚", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101021, Name: "智瑞", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101022, Name: "智虹", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101023, Name: "智萱", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101024, Name: "智皓", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101025, Name: "智薇", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101026, Name: "智希", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101027, Name: "智梅", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101050, Name: "WeJack", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+	{VoiceType: 101051, Name: "WeRose", Codecs: defaultVoiceCodecs, SampleRates: defaultSampleRates},
+}
+
+// VoiceTypes returns the catalog of known voices
+func VoiceTypes() []VoiceInfo {
+	voices := make([]VoiceInfo, len(voiceCatalog))
+	copy(voices, voiceCatalog)
+	return voices
+}
+
+// LookupVoice returns the catalog entry of voiceType
+func LookupVoice(voiceType int64) (VoiceInfo, bool) {
+	for _, v := range voiceCatalog {
+		if v.VoiceType == voiceType {
+			return v, true
+		}
+	}
+	return VoiceInfo{}, false
+}
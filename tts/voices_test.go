@@ -0,0 +1,63 @@
+package tts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVoiceType(t *testing.T) {
+	tests := []struct {
+		name      string
+		voiceType int64
+		opts      []Option
+		wantErr   bool
+	}{
+		{"known", 101001, nil, false},
+		{"unknown", 999999, nil, true},
+		{"unknown without validation", 999999, []Option{WithoutVoiceValidation()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, tt.opts...)
+			s.VoiceType = tt.voiceType
+			if err := s.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVoiceTypesIsACopy(t *testing.T) {
+	voices := VoiceTypes()
+	if len(voices) == 0 {
+		t.Fatal("VoiceTypes() is empty")
+	}
+	voices[0].Name = "changed"
+	if v, _ := LookupVoice(voices[0].VoiceType); v.Name == "changed" {
+		t.Error("changing the result of VoiceTypes() changed the catalog")
+	}
+}
+
+func TestValidateSampleRate(t *testing.T) {
+	s := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.VoiceType = 101001
+	s.SampleRate = 24000
+	err := s.Validate()
+	if err == nil || !strings.Contains(err.Error(), "allowed: 8000, 16000") {
+		t.Errorf("Validate() error = %v, want the allowed rates listed", err)
+	}
+
+	s = NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{}, WithoutRateValidation())
+	s.VoiceType = 101001
+	s.SampleRate = 24000
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() with WithoutRateValidation error = %v", err)
+	}
+
+	s = NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	s.VoiceType = 101001
+	s.SampleRate = 8000
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate() with a supported rate error = %v", err)
+	}
+}
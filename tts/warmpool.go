@@ -0,0 +1,172 @@
+package tts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/showntop/tencentcloud-speech-sdk-go/common"
+)
+
+// defaultWarmPoolMaxIdle stays below the delay after which the server drops a session
+// that received no text
+const defaultWarmPoolMaxIdle = 10 * time.Second
+
+// WarmPool keeps up to size connections past the handshake and ready frame, so a
+// synthesis checked out with Get only has to send its text. Each connection carries its
+// own session and is used once; the pool connects a replacement in the background.
+//
+// Warm connections idle for maxIdle are closed and re-established, before the server
+// times them out. Configure the synthesizers through the options, an Option may set any
+// exported field, e.g. func(s *SpeechWsv2Synthesizer) { s.VoiceType = 101001 }.
+type WarmPool struct {
+	appID      int64
+	credential *common.Credential
+	opts       []Option
+	size       int
+	maxIdle    time.Duration
+
+	mutex   sync.Mutex
+	idle    []*warmConn
+	warming int
+	closed  bool
+}
+
+type warmConn struct {
+	synthesizer *SpeechWsv2Synthesizer
+	conn        *websocket.Conn
+	msg         *SpeechWsv2SynthesisResponse
+	expiry      *time.Timer
+}
+
+// NewWarmPool creates instance of WarmPool and starts warming size connections. A zero
+// maxIdle uses 10 seconds.
+func NewWarmPool(appID int64, credential *common.Credential, size int, maxIdle time.Duration, opts ...Option) *WarmPool {
+	if maxIdle <= 0 {
+		maxIdle = defaultWarmPoolMaxIdle
+	}
+	p := &WarmPool{
+		appID:      appID,
+		credential: credential,
+		opts:       opts,
+		size:       size,
+		maxIdle:    maxIdle,
+	}
+	p.fill()
+	return p
+}
+
+// Get returns a started synthesizer reporting to listener, use it as after Prepare. When
+// no warm connection is available Get connects as Prepare would.
+func (p *WarmPool) Get(listener SpeechWsv2SynthesisListener) (*SpeechWsv2Synthesizer, error) {
+	w := p.take()
+	p.fill()
+	if w == nil {
+		synthesizer := NewSpeechWsv2Synthesizer(p.appID, p.credential, listener, p.opts...)
+		if err := synthesizer.Prepare(); err != nil {
+			return nil, err
+		}
+		return synthesizer, nil
+	}
+	synthesizer := w.synthesizer
+	synthesizer.mutex.Lock()
+	defer synthesizer.mutex.Unlock()
+	synthesizer.listener = listener
+	if err := synthesizer.startConn(w.conn, w.msg); err != nil {
+		return nil, err
+	}
+	return synthesizer, nil
+}
+
+// Idle returns the number of warm connections ready to be checked out
+func (p *WarmPool) Idle() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.idle)
+}
+
+// Close closes the warm connections and stops warming new ones. Synthesizers already
+// returned by Get are not affected.
+func (p *WarmPool) Close() error {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mutex.Unlock()
+	for _, w := range idle {
+		w.expiry.Stop()
+		w.conn.Close()
+	}
+	return nil
+}
+
+func (p *WarmPool) take() *warmConn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.idle) == 0 {
+		return nil
+	}
+	w := p.idle[0]
+	p.idle = p.idle[1:]
+	w.expiry.Stop()
+	return w
+}
+
+// fill starts warming connections until size are idle or in progress
+func (p *WarmPool) fill() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return
+	}
+	for ; len(p.idle)+p.warming < p.size; p.warming++ {
+		go p.warm()
+	}
+}
+
+func (p *WarmPool) warm() {
+	synthesizer := NewSpeechWsv2Synthesizer(p.appID, p.credential, nil, p.opts...)
+	synthesizer.mutex.Lock()
+	var conn *websocket.Conn
+	var msg *SpeechWsv2SynthesisResponse
+	err := synthesizer.prepareRequest()
+	if err == nil {
+		conn, msg, err = synthesizer.connect(context.Background())
+	}
+	synthesizer.mutex.Unlock()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.warming--
+	if err != nil {
+		// retried on the next Get
+		return
+	}
+	if p.closed {
+		conn.Close()
+		return
+	}
+	w := &warmConn{synthesizer: synthesizer, conn: conn, msg: msg}
+	w.expiry = time.AfterFunc(p.maxIdle, func() { p.expire(w) })
+	p.idle = append(p.idle, w)
+}
+
+// expire replaces w if it is still idle
+func (p *WarmPool) expire(w *warmConn) {
+	p.mutex.Lock()
+	found := false
+	for i, c := range p.idle {
+		if c == w {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			found = true
+			break
+		}
+	}
+	p.mutex.Unlock()
+	if !found {
+		return
+	}
+	w.conn.Close()
+	p.fill()
+}
@@ -0,0 +1,55 @@
+package tts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWarmPoolReusesWarmConnection(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	server := mockServer(t, func(conn *websocket.Conn) {
+		time.Sleep(delay)
+		handshake(conn)
+		drain(conn)
+	})
+	pool := NewWarmPool(0, testCredential, 1, time.Minute)
+	defer pool.Close()
+	waitFor(t, "a warm connection", func() bool { return pool.Idle() == 1 })
+	warmed := server.requests()[0].URL.Query().Get("SessionId")
+
+	start := time.Now()
+	s, err := pool.Get(&recordListener{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	warm := time.Since(start)
+	defer s.Abort()
+	if s.SessionId != warmed {
+		t.Errorf("Get() returned session %q, want the warmed session %q", s.SessionId, warmed)
+	}
+
+	start = time.Now()
+	cold := NewSpeechWsv2Synthesizer(0, testCredential, &recordListener{})
+	if err := cold.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	coldLatency := time.Since(start)
+	cold.Abort()
+	if warm >= coldLatency || warm >= delay {
+		t.Errorf("Get() from a warm pool took %v, a cold Prepare %v, want the warm one faster", warm, coldLatency)
+	}
+}
+
+func TestWarmPoolReplacesExpired(t *testing.T) {
+	server := mockServer(t, func(conn *websocket.Conn) {
+		handshake(conn)
+		drain(conn)
+	})
+	pool := NewWarmPool(0, testCredential, 1, 20*time.Millisecond)
+	defer pool.Close()
+	waitFor(t, "a warm connection", func() bool { return pool.Idle() == 1 })
+	waitFor(t, "the connection re-established", func() bool { return len(server.requests()) >= 2 })
+	waitFor(t, "a warm connection", func() bool { return pool.Idle() == 1 })
+}